@@ -1,8 +1,7 @@
 package main
 
 import (
-	"io"
-	"net/http"
+	"fmt"
 	"strings"
 )
 
@@ -12,32 +11,37 @@ type Author struct {
 	Downloads int
 }
 
-func (author Author) Thumbnail() string {
-	url := "https://mods.factorio.com/user/" + author.Name
-
-	resp, err := http.Get(url)
-	if err != nil {
-		return ""
-	}
-	html, err := io.ReadAll(resp.Body)
+// Thumbnail scrapes the author's avatar from their portal page. It is empty
+// for authors without an avatar, and when the page can't be parsed.
+func (author Author) Thumbnail() (string, error) {
+	content, err := portal.GetAuthorPage(author.Name)
 	if err != nil {
-		return ""
+		return "", err
 	}
 
-	content := string(html)
-	i := strings.Index(content, "author-card-thumbnail")
-	i += strings.Index(content[i:], "src=\"") + 5
-	j := i + strings.Index(content[i:], "\"")
+	_, thumbnail, found := strings.Cut(content, "author-card-thumbnail")
+	if found {
+		_, thumbnail, found = strings.Cut(thumbnail, "src=\"")
+	}
+	if found {
+		thumbnail, _, found = strings.Cut(thumbnail, "\"")
+	}
+	if !found {
+		return "", fmt.Errorf("no thumbnail on the page of author %s", author.Name)
+	}
 
-	thumbnail := content[i:j]
 	if thumbnail == "/static/no-avatar.png" {
-		return ""
+		return "", nil
 	}
-	return thumbnail
+	return thumbnail, nil
 }
 
 func (author Author) URL() string {
-	return "https://mods.factorio.com/user/" + author.Name
+	return UserURL(author.Name)
+}
+
+func UserURL(name string) string {
+	return config.PortalURL + "/user/" + name
 }
 
 func AuthorAutocomplete(value string) []*Author {
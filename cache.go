@@ -0,0 +1,131 @@
+package main
+
+import (
+	"container/list"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// FullModCache is an LRU cache of full mod details with a TTL. Entries are
+// also dropped when the mod list shows a newer release than the cached one.
+type FullModCache struct {
+	mu       sync.Mutex
+	filename string
+	capacity int
+	ttl      time.Duration
+	entries  map[string]*list.Element
+	order    *list.List
+}
+
+type fullModEntry struct {
+	Mod     FullMod   `json:"mod"`
+	Fetched time.Time `json:"fetched"`
+}
+
+// NewFullModCache creates a cache holding up to capacity mods. A capacity of
+// zero disables caching. If filename is set, Load and Save persist the cache.
+func NewFullModCache(capacity int, ttl time.Duration, filename string) *FullModCache {
+	return &FullModCache{
+		filename: filename,
+		capacity: capacity,
+		ttl:      ttl,
+		entries:  map[string]*list.Element{},
+		order:    list.New(),
+	}
+}
+
+// Get returns a copy of the cached mod if it is present and not expired.
+func (cache *FullModCache) Get(name string) (FullMod, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	element, ok := cache.entries[name]
+	if !ok {
+		return FullMod{}, false
+	}
+	entry := element.Value.(*fullModEntry)
+	if time.Since(entry.Fetched) > cache.ttl {
+		cache.remove(element)
+		return FullMod{}, false
+	}
+	cache.order.MoveToFront(element)
+	return entry.Mod.Clone(), true
+}
+
+// Put caches a copy of fullMod, so later changes by the caller don't leak
+// into the cache.
+func (cache *FullModCache) Put(fullMod FullMod) {
+	cache.put(&fullModEntry{Mod: fullMod.Clone(), Fetched: time.Now()})
+}
+
+func (cache *FullModCache) put(entry *fullModEntry) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.capacity <= 0 || entry.Mod.Mod == nil {
+		return
+	}
+	if element, ok := cache.entries[entry.Mod.Name]; ok {
+		cache.remove(element)
+	}
+	cache.entries[entry.Mod.Name] = cache.order.PushFront(entry)
+	for cache.order.Len() > cache.capacity {
+		cache.remove(cache.order.Back())
+	}
+}
+
+func (cache *FullModCache) remove(element *list.Element) {
+	cache.order.Remove(element)
+	delete(cache.entries, element.Value.(*fullModEntry).Mod.Name)
+}
+
+// Refresh drops cached mods whose latest release in modList is newer than the
+// cached one.
+func (cache *FullModCache) Refresh(modList []Mod) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	for _, mod := range modList {
+		element, ok := cache.entries[mod.Name]
+		if !ok {
+			continue
+		}
+		cached := element.Value.(*fullModEntry).Mod
+		if CompareVersions(mod.LatestRelease.Version, cached.LatestRelease.Version) > 0 {
+			cache.remove(element)
+		}
+	}
+}
+
+// Load reads a persisted cache, ignoring a missing file.
+func (cache *FullModCache) Load() error {
+	if cache.filename == "" {
+		return nil
+	}
+	var entries []*fullModEntry
+	if err := ReadJson(cache.filename, &entries); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if time.Since(entries[i].Fetched) <= cache.ttl {
+			cache.put(entries[i])
+		}
+	}
+	return nil
+}
+
+// Save persists the cache, most recently used first.
+func (cache *FullModCache) Save() error {
+	if cache.filename == "" {
+		return nil
+	}
+	cache.mu.Lock()
+	entries := make([]*fullModEntry, 0, cache.order.Len())
+	for element := cache.order.Front(); element != nil; element = element.Next() {
+		entries = append(entries, element.Value.(*fullModEntry))
+	}
+	cache.mu.Unlock()
+	return WriteJson(cache.filename, entries)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func cacheTestMod(name, version string) FullMod {
+	release := Release{Version: version, InfoJson: InfoJson{FactorioVersion: "2.0", Dependencies: []string{"base >= 2.0"}}}
+	return FullMod{
+		Mod: &Mod{
+			Name:          name,
+			Tags:          []string{"logistics"},
+			Dependencies:  map[string]bool{"base": true},
+			LatestRelease: release,
+		},
+		Releases: []Release{release},
+	}
+}
+
+func TestFullModCacheCopies(t *testing.T) {
+	cache := NewFullModCache(10, time.Hour, "")
+	fullMod := cacheTestMod("a", "1.0.0")
+	cache.Put(fullMod)
+
+	fullMod.Title = "changed"
+	fullMod.Tags[0] = "changed"
+	fullMod.Dependencies["changed"] = true
+	fullMod.LatestRelease.InfoJson.Dependencies[0] = "changed"
+	fullMod.Releases[0].InfoJson.Dependencies[0] = "changed"
+
+	got, ok := cache.Get("a")
+	if !ok {
+		t.Fatal("mod not cached")
+	}
+	check := func(got FullMod) {
+		t.Helper()
+		if got.Title != "" || got.Tags[0] != "logistics" || got.Dependencies["changed"] ||
+			got.LatestRelease.InfoJson.Dependencies[0] != "base >= 2.0" || got.Releases[0].InfoJson.Dependencies[0] != "base >= 2.0" {
+			t.Errorf("cached mod was changed: %+v %+v", *got.Mod, got.Releases)
+		}
+	}
+	check(got)
+
+	got.Title = "changed"
+	got.Tags[0] = "changed"
+	got.Dependencies["changed"] = true
+	got.LatestRelease.InfoJson.Dependencies[0] = "changed"
+	got.Releases[0].InfoJson.Dependencies[0] = "changed"
+	got, _ = cache.Get("a")
+	check(got)
+}
+
+func TestFullModCacheEviction(t *testing.T) {
+	cache := NewFullModCache(2, time.Hour, "")
+	cache.Put(cacheTestMod("a", "1.0.0"))
+	cache.Put(cacheTestMod("b", "1.0.0"))
+	cache.Get("a")
+	cache.Put(cacheTestMod("c", "1.0.0"))
+
+	for name, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok := cache.Get(name); ok != want {
+			t.Errorf("%s cached = %v, want %v", name, ok, want)
+		}
+	}
+
+	disabled := NewFullModCache(0, time.Hour, "")
+	disabled.Put(cacheTestMod("a", "1.0.0"))
+	if _, ok := disabled.Get("a"); ok {
+		t.Error("disabled cache returned a mod")
+	}
+}
+
+func TestFullModCacheTTL(t *testing.T) {
+	cache := NewFullModCache(10, time.Hour, "")
+	cache.put(&fullModEntry{Mod: cacheTestMod("old", "1.0.0"), Fetched: time.Now().Add(-2 * time.Hour)})
+	cache.Put(cacheTestMod("new", "1.0.0"))
+
+	if _, ok := cache.Get("old"); ok {
+		t.Error("expired mod returned")
+	}
+	if _, ok := cache.Get("new"); !ok {
+		t.Error("fresh mod not returned")
+	}
+}
+
+func TestFullModCacheRefresh(t *testing.T) {
+	cache := NewFullModCache(10, time.Hour, "")
+	cache.Put(cacheTestMod("a", "1.0.0"))
+	cache.Put(cacheTestMod("b", "1.0.0"))
+
+	cache.Refresh([]Mod{*cacheTestMod("a", "1.1.0").Mod, *cacheTestMod("b", "1.0.0").Mod})
+	if _, ok := cache.Get("a"); ok {
+		t.Error("outdated mod not dropped")
+	}
+	if _, ok := cache.Get("b"); !ok {
+		t.Error("current mod dropped")
+	}
+}
+
+func TestModRequestCachesLatestRelease(t *testing.T) {
+	fake := setupTest(t)
+	list, err := portal.ListMods()
+	if err != nil {
+		t.Fatal(err)
+	}
+	CacheModList(list.Results)
+	mod := *mods["example-mod"]
+
+	if _, err := mod.Request(true); err != nil {
+		t.Fatal(err)
+	}
+	cached, ok := modCache.Get("example-mod")
+	if !ok {
+		t.Fatal("mod not cached")
+	}
+	if cached.LatestRelease.Version != mod.LatestRelease.Version {
+		t.Errorf("cached latest release %q, want %q", cached.LatestRelease.Version, mod.LatestRelease.Version)
+	}
+
+	// The cached mod is current, so it must survive a refresh.
+	modCache.Refresh(list.Results)
+	if _, err := mod.Request(true); err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.Requests("/api/mods/example-mod/full"); got != 1 {
+		t.Errorf("got %d requests, want 1", got)
+	}
+
+	mod.LatestRelease.Version = "9.0.0"
+	modCache.Refresh([]Mod{mod})
+	if _, err := mod.Request(true); err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.Requests("/api/mods/example-mod/full"); got != 2 {
+		t.Errorf("got %d requests after a new release, want 2", got)
+	}
+}
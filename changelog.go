@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ChangelogEntry is one version section of a Factorio changelog.txt.
+type ChangelogEntry struct {
+	Version    string
+	Date       string
+	Categories map[string][]string
+}
+
+// changelogCategoryOrder is the order the game lists the standard categories
+// in. Other categories follow alphabetically.
+var changelogCategoryOrder = []string{
+	"Major Features", "Features", "Minor Features", "Graphics", "Sounds",
+	"Optimizations", "Balancing", "Combat Balancing", "Circuit Network",
+	"Changes", "Bugfixes", "Modding", "Scripting", "Gui", "Control",
+	"Translation", "Debug", "Ease of use", "Info", "Locale",
+}
+
+const changelogOtherCategory = "Other"
+
+var (
+	changelogSeparator = regexp.MustCompile(`^-{99}\s*$`)
+	changelogVersion   = regexp.MustCompile(`^\s*Version:\s*(.*?)\s*$`)
+	changelogDate      = regexp.MustCompile(`^\s*Date:\s*(.*?)\s*$`)
+	changelogCategory  = regexp.MustCompile(`^\s{0,3}([^\s-].*?)\s*:\s*$`)
+	changelogItem      = regexp.MustCompile(`^\s*-\s*(.*?)\s*$`)
+	changelogIssue     = regexp.MustCompile(`#[0-9]+`)
+	changelogPull      = regexp.MustCompile(`![0-9]+`)
+)
+
+// ParseChangelog parses a changelog in the format described on the Factorio
+// wiki. It tolerates CRLF line endings, tabs, missing separators and
+// inconsistent indentation; lines it can't place are ignored.
+func ParseChangelog(text string) []ChangelogEntry {
+	var entries []ChangelogEntry
+	var entry *ChangelogEntry
+	var category string
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(strings.ReplaceAll(line, "\t", "  "), " \r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if changelogSeparator.MatchString(line) {
+			entry = nil
+			continue
+		}
+		if match := changelogVersion.FindStringSubmatch(line); match != nil {
+			entries = append(entries, ChangelogEntry{Version: match[1], Categories: map[string][]string{}})
+			entry = &entries[len(entries)-1]
+			category = ""
+			continue
+		}
+		if entry == nil {
+			continue
+		}
+		if match := changelogDate.FindStringSubmatch(line); match != nil && entry.Date == "" && category == "" {
+			entry.Date = match[1]
+			continue
+		}
+		if match := changelogCategory.FindStringSubmatch(line); match != nil {
+			category = match[1]
+			continue
+		}
+		if category == "" {
+			category = changelogOtherCategory
+		}
+		items := entry.Categories[category]
+		if match := changelogItem.FindStringSubmatch(line); match != nil {
+			entry.Categories[category] = append(items, match[1])
+		} else if len(items) > 0 {
+			items[len(items)-1] += "\n" + strings.TrimSpace(line)
+		} else {
+			entry.Categories[category] = append(items, strings.TrimSpace(line))
+		}
+	}
+
+	return entries
+}
+
+// MergeChangelog merges the entries after from, up to and including to, into
+// one set of categories. Each item is suffixed with its version. An empty from
+// includes everything up to to.
+func MergeChangelog(entries []ChangelogEntry, from, to string) (map[string][]string, int) {
+	merged := map[string][]string{}
+	count := 0
+	for _, entry := range entries {
+		if from != "" && CompareVersions(entry.Version, from) <= 0 {
+			continue
+		}
+		if CompareVersions(entry.Version, to) > 0 {
+			continue
+		}
+		count++
+		for _, name := range ChangelogCategories(entry.Categories) {
+			for _, item := range entry.Categories[name] {
+				merged[name] = append(merged[name], fmt.Sprintf("%s (%s)", item, entry.Version))
+			}
+		}
+	}
+	return merged, count
+}
+
+// ChangelogCategories returns the categories in the order the game shows them.
+func ChangelogCategories(categories map[string][]string) []string {
+	names := SortedKeys(categories)
+	slices.SortStableFunc(names, func(a, b string) int {
+		i, j := slices.Index(changelogCategoryOrder, a), slices.Index(changelogCategoryOrder, b)
+		if i == -1 {
+			i = len(changelogCategoryOrder)
+		}
+		if j == -1 {
+			j = len(changelogCategoryOrder)
+		}
+		return i - j
+	})
+	return names
+}
+
+// FormatChangelogCategory renders one category as Discord markdown, linking
+// issue and pull request references when sourceURL is set.
+func FormatChangelogCategory(name string, items []string, sourceURL string) string {
+	lines := []string{fmt.Sprintf("**%s:**", name)}
+	for _, item := range items {
+		item = strings.ReplaceAll(item, "__", "\\__")
+		item = strings.ReplaceAll(item, "\n", "\n  ")
+		if sourceURL != "" {
+			item = LinkIssues(item, sourceURL)
+		}
+		lines = append(lines, "- "+item)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// FormatChangelogCategories renders every category as Discord markdown.
+func FormatChangelogCategories(categories map[string][]string, sourceURL string) string {
+	var sections []string
+	for _, name := range ChangelogCategories(categories) {
+		sections = append(sections, FormatChangelogCategory(name, categories[name], sourceURL))
+	}
+	return strings.Join(sections, "\n")
+}
+
+// LinkIssues links #123 and !123 references to the source repository's issues
+// and pull requests.
+func LinkIssues(s, sourceURL string) string {
+	s = changelogIssue.ReplaceAllStringFunc(s, func(match string) string {
+		return fmt.Sprintf("[%s](%s/issues/%s)", match, sourceURL, match[1:])
+	})
+	return changelogPull.ReplaceAllStringFunc(s, func(match string) string {
+		return fmt.Sprintf("[%s](%s/pulls/%s)", match, sourceURL, match[1:])
+	})
+}
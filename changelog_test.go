@@ -0,0 +1,139 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const changelogSeparatorLine = "---------------------------------------------------------------------------------------------------"
+
+func TestParseChangelog(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		entries []ChangelogEntry
+	}{{
+		name: "standard",
+		text: changelogSeparatorLine + "\nVersion: 1.1.0\nDate: 2024-10-21\n  Features:\n    - Added a thing.\n  Bugfixes:\n    - Fixed a crash.\n    - Fixed another crash.\n" +
+			changelogSeparatorLine + "\nVersion: 1.0.0\nDate: 2024-01-01\n  Features:\n    - Initial release.\n",
+		entries: []ChangelogEntry{{
+			Version:    "1.1.0",
+			Date:       "2024-10-21",
+			Categories: map[string][]string{"Features": {"Added a thing."}, "Bugfixes": {"Fixed a crash.", "Fixed another crash."}},
+		}, {
+			Version:    "1.0.0",
+			Date:       "2024-01-01",
+			Categories: map[string][]string{"Features": {"Initial release."}},
+		}},
+	}, {
+		name: "crlf",
+		text: changelogSeparatorLine + "\r\nVersion: 1.0.0\r\nDate: 2024-01-01\r\n  Features:\r\n    - Initial release.\r\n",
+		entries: []ChangelogEntry{{
+			Version:    "1.0.0",
+			Date:       "2024-01-01",
+			Categories: map[string][]string{"Features": {"Initial release."}},
+		}},
+	}, {
+		name: "tabs",
+		text: changelogSeparatorLine + "\nVersion: 1.0.0\n\tFeatures:\n\t\t- Initial release.\n",
+		entries: []ChangelogEntry{{
+			Version:    "1.0.0",
+			Categories: map[string][]string{"Features": {"Initial release."}},
+		}},
+	}, {
+		name: "missing separators",
+		text: "Version: 1.1.0\n  Changes:\n    - Second.\nVersion: 1.0.0\n  Changes:\n    - First.\n",
+		entries: []ChangelogEntry{{
+			Version:    "1.1.0",
+			Categories: map[string][]string{"Changes": {"Second."}},
+		}, {
+			Version:    "1.0.0",
+			Categories: map[string][]string{"Changes": {"First."}},
+		}},
+	}, {
+		name: "inconsistent indentation",
+		text: "Version: 1.0.0\nFeatures:\n- One.\n      - Two.\n   Bugfixes:\n  -Three.\n",
+		entries: []ChangelogEntry{{
+			Version:    "1.0.0",
+			Categories: map[string][]string{"Features": {"One.", "Two."}, "Bugfixes": {"Three."}},
+		}},
+	}, {
+		name: "multi-line items",
+		text: "Version: 1.0.0\n  Features:\n    - First line\n      second line.\n    - Next item.\n",
+		entries: []ChangelogEntry{{
+			Version:    "1.0.0",
+			Categories: map[string][]string{"Features": {"First line\nsecond line.", "Next item."}},
+		}},
+	}, {
+		name: "items without a category",
+		text: "Version: 1.0.0\n    - Loose item.\n  Plain text.\n",
+		entries: []ChangelogEntry{{
+			Version:    "1.0.0",
+			Categories: map[string][]string{changelogOtherCategory: {"Loose item.\nPlain text."}},
+		}},
+	}, {
+		name: "date only before categories",
+		text: "Version: 1.0.0\n  Changes:\n    - Date: not a date.\n",
+		entries: []ChangelogEntry{{
+			Version:    "1.0.0",
+			Categories: map[string][]string{"Changes": {"Date: not a date."}},
+		}},
+	}, {
+		name: "lines outside a version are ignored",
+		text: "Some preamble.\n  - Stray item.\n" + changelogSeparatorLine + "\n  - After separator.\nVersion: 1.0.0\n  Info:\n    - Kept.\n" + changelogSeparatorLine + "\n  - Trailing.\n",
+		entries: []ChangelogEntry{{
+			Version:    "1.0.0",
+			Categories: map[string][]string{"Info": {"Kept."}},
+		}},
+	}, {
+		name: "empty",
+		text: "",
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entries := ParseChangelog(test.text)
+			if !reflect.DeepEqual(entries, test.entries) {
+				t.Errorf("got %+v\nwant %+v", entries, test.entries)
+			}
+		})
+	}
+}
+
+func TestFormatChangelogCategories(t *testing.T) {
+	categories := map[string][]string{
+		"Bugfixes": {"Fixed #12 and !3."},
+		"Custom":   {"Something __bold__.", "Two\nlines."},
+		"Features": {"Added a thing."},
+	}
+	want := "**Features:**\n- Added a thing.\n" +
+		"**Bugfixes:**\n- Fixed [#12](https://example.com/issues/12) and [!3](https://example.com/pulls/3).\n" +
+		"**Custom:**\n- Something \\__bold\\__.\n- Two\n  lines."
+	if got := FormatChangelogCategories(categories, "https://example.com"); got != want {
+		t.Errorf("got %q\nwant %q", got, want)
+	}
+}
+
+func FuzzParseChangelog(f *testing.F) {
+	f.Add(changelogSeparatorLine + "\nVersion: 1.0.0\nDate: 2024-01-01\n  Features:\n    - Initial release.\n")
+	f.Add(changelogSeparatorLine + "\r\nVersion: 1.0.0\r\nDate: 2024-01-01\r\n  Bugfixes:\r\n    - Fixed #1.\r\n")
+	f.Add("Version: 1.0.0\n\tFeatures:\n\t\t- Tabbed.\n")
+	f.Add("Version: 1.1.0\n  Changes:\n    - Second.\nVersion: 1.0.0\n  Changes:\n    - First.\n")
+	f.Add("Version: 1.0.0\n  Features:\n    - First line\n      second line.\n    - " + strings.Repeat("long ", 1000) + "\n")
+	f.Add("Version:\nDate:\n:\n-\n  -\n!1 #2 __")
+
+	f.Fuzz(func(t *testing.T, text string) {
+		entries := ParseChangelog(text)
+		for _, entry := range entries {
+			description := FormatChangelogCategories(entry.Categories, "https://example.com")
+			pages := PageEmbeds(discordgo.MessageEmbed{Title: "Example"}, strings.Split(description, "\n"), 4096)
+			for n, page := range pages {
+				if len(page.Description) > 4096 {
+					t.Fatalf("page %d is %d bytes long", n, len(page.Description))
+				}
+			}
+		}
+	})
+}
@@ -1,50 +1,116 @@
 package main
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"log/slog"
 	"net/http"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 )
 
-func InitCommands() ([]*discordgo.ApplicationCommand, map[string]func(*discordgo.InteractionCreate, discordgo.ApplicationCommandInteractionData)) {
+func InitCommands() ([]*discordgo.ApplicationCommand, *Router) {
 	var commands []*CommandData
 
-	mod := NewCommand("mod", "Links a mod from the mod portal")
+	// The author pager reuses the thumbnail passed in its arguments, so page
+	// clicks don't scrape the author's page again.
+	RegisterPager("author", func(i *discordgo.InteractionCreate, args []string) ([]*discordgo.MessageEmbed, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("invalid author page arguments %v", args)
+		}
+		author, ok := authors[args[0]]
+		if !ok {
+			return nil, fmt.Errorf("unknown author %s", args[0])
+		}
+		return AuthorPages(author, args[1]), nil
+	})
+	RegisterPager("changelog", func(i *discordgo.InteractionCreate, args []string) ([]*discordgo.MessageEmbed, error) {
+		if len(args) != 4 || mods[args[0]] == nil {
+			return nil, fmt.Errorf("invalid changelog arguments %q", args)
+		}
+		fullMod, err := mods[args[0]].Request(true)
+		if err != nil {
+			return nil, err
+		}
+		release := fullMod.GetRelease(args[1])
+		if release == nil {
+			return nil, fmt.Errorf("unknown version %s of %s", args[1], args[0])
+		}
+		return ChangelogPages(fullMod, *release, args[2], args[3] == "range"), nil
+	})
+	RegisterPager("dependents", func(i *discordgo.InteractionCreate, args []string) ([]*discordgo.MessageEmbed, error) {
+		mod := mods[args[0]]
+		if mod == nil {
+			return nil, fmt.Errorf("unknown mod %s", args[0])
+		}
+		return DependentsPages(mod), nil
+	})
+	RegisterPager("dependencies", func(i *discordgo.InteractionCreate, args []string) ([]*discordgo.MessageEmbed, error) {
+		mod := mods[args[0]]
+		if mod == nil {
+			return nil, fmt.Errorf("unknown mod %s", args[0])
+		}
+		fullMod, err := mod.Request(true)
+		if err != nil {
+			return nil, err
+		}
+		release := fullMod.GetRelease(args[1])
+		if release == nil {
+			return nil, fmt.Errorf("unknown version %s of %s", args[1], args[0])
+		}
+		return DependenciesPages(fullMod, *release), nil
+	})
+	// The subscriptions pager lists whoever clicks, never a user from the
+	// custom ID.
+	RegisterPager("subscriptions", func(i *discordgo.InteractionCreate, args []string) ([]*discordgo.MessageEmbed, error) {
+		return SubscriptionsPages(InteractionUserID(i), i.GuildID)
+	})
+	RegisterPager("tracklist", func(i *discordgo.InteractionCreate, args []string) ([]*discordgo.MessageEmbed, error) {
+		guildData, err := guildStore.Get(i.GuildID)
+		if err != nil {
+			return nil, err
+		}
+		route := guildData.GetRoute(args[0])
+		if route == nil {
+			return nil, fmt.Errorf("unknown route %s", args[0])
+		}
+		return TrackListPages(guildData, args[0], route), nil
+	})
+
+	mod := NewCommand("mod", "Links a mod from the mod portal").SetDeferred()
 	commands = append(commands, mod)
 	mod.AddOption("mod", "Mod name").SetAutocomplete()
 	mod.AddOption("author", "Author filter").SetOptional().SetAutocomplete()
 	mod.AddOption("version", "Factorio version filter").SetOptional().SetAutocomplete()
-	mod.Handler = func(i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+	mod.Handler = func(m Messenger, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
 		options := MapOptions(data.Options)
 		switch i.Type {
 		case discordgo.InteractionApplicationCommand:
 			name := options["mod"].StringValue()
 			mod := mods[name]
 			if mod == nil {
-				RespondError(i, "Invalid Mod Name", fmt.Sprintf("The mod %s was not found.", name))
+				RespondError(m, i, "Invalid Mod Name", fmt.Sprintf("The mod %s was not found.", name))
 				return
 			}
 
 			fullMod, err := mod.Request(false)
 			if err != nil {
-				RespondDefaultError(i)
+				RespondDefaultError(m, i)
 				return
 			}
 
 			fields := []*discordgo.MessageEmbedField{{
-				Value:  fmt.Sprintf("**Author:** [%s](https://mods.factorio.com/user/%s)", mod.Owner, mod.Owner),
+				Value:  fmt.Sprintf("**Author:** [%s](%s)", mod.Owner, UserURL(mod.Owner)),
 				Inline: true,
 			}, {
 				Value:  fmt.Sprintf("**Downloads:** %d", mod.DownloadsCount),
 				Inline: true,
 			}}
 
-			RespondEmbed(i, discordgo.MessageEmbed{
+			RespondEmbed(m, i, discordgo.MessageEmbed{
 				Title:       Truncate(mod.Title, 256),
 				URL:         mod.URL(),
 				Description: Truncate(mod.Summary, 2048),
@@ -63,7 +129,7 @@ func InitCommands() ([]*discordgo.ApplicationCommand, map[string]func(*discordgo
 				if options["author"] != nil {
 					modArr = versions["all"]
 				} else {
-					modArr = VersionFilter(options["version"])
+					modArr = VersionFilter(options["version"], GuildVersion(i.GuildID))
 				}
 				modArr = AuthorFilter(modArr, options["author"])
 				modArr = ModAutocomplete(modArr, focused.StringValue())
@@ -72,67 +138,47 @@ func InitCommands() ([]*discordgo.ApplicationCommand, map[string]func(*discordgo
 				authorArr := AuthorAutocomplete(focused.StringValue())
 				choices = AuthorChoices(authorArr)
 			case "version":
-				for version := range versions {
-					choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: version, Value: version})
-				}
+				choices = StringChoices(append(FactorioVersions(), "all"))
 			}
 
-			RespondChoices(i, choices)
+			RespondChoices(m, i, choices)
 		}
 	}
 
-	author := NewCommand("author", "Links an author from the mod portal")
+	author := NewCommand("author", "Links an author from the mod portal").SetDeferred()
 	commands = append(commands, author)
 	author.AddOption("name", "Author Name").SetAutocomplete()
-	author.Handler = func(i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+	author.Handler = func(m Messenger, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
 		options := MapOptions(data.Options)
 		switch i.Type {
 		case discordgo.InteractionApplicationCommand:
 			name := options["name"].StringValue()
 			author, ok := authors[name]
 			if !ok {
-				RespondError(i, "Invalid Author Name", fmt.Sprintf("The author `%s` was not found.", name))
+				RespondError(m, i, "Invalid Author Name", fmt.Sprintf("The author `%s` was not found.", name))
 				return
 			}
 
-			slices.SortFunc(author.Mods, func(a, b Mod) int {
-				return Ternary(a.LatestRelease.ReleasedAt > b.LatestRelease.ReleasedAt, -1, 1)
-			})
-
-			description := "**Recent releases:**"
-			for i := 0; i < 5 && i < len(author.Mods); i++ {
-				mod := author.Mods[i]
-				latest := mod.LatestRelease
-				description += fmt.Sprintf("\n- [%s](%s) - %s - %s", mod.Title, mod.URL(), latest.Version, Timestamp(latest.ReleasedAt))
+			thumbnail, err := author.Thumbnail()
+			if err != nil {
+				slog.Warn("Could not get author thumbnail", "author", name, "err", err)
 			}
-
-			RespondEmbed(i, discordgo.MessageEmbed{
-				Title:       name,
-				URL:         author.URL(),
-				Description: description,
-				Color:       colors.Gold,
-				Thumbnail:   &discordgo.MessageEmbedThumbnail{URL: author.Thumbnail()},
-				Fields: []*discordgo.MessageEmbedField{{
-					Value:  fmt.Sprintf("**Total Mods:** %d", len(author.Mods)),
-					Inline: true,
-				}, {
-					Value:  fmt.Sprintf("**Total Downloads:** %d", author.Downloads),
-					Inline: true,
-				}},
-			})
+			RespondPages(m, i, "author", []string{name, thumbnail}, AuthorPages(author, thumbnail), 0)
 
 		case discordgo.InteractionApplicationCommandAutocomplete:
 			name := options["name"].StringValue()
 			authorArr := AuthorAutocomplete(name)
-			RespondChoices(i, AuthorChoices(authorArr))
+			RespondChoices(m, i, AuthorChoices(authorArr))
 		}
 	}
 
-	changelog := NewCommand("changelog", "Displays the changelog for a specific version of a mod")
+	changelog := NewCommand("changelog", "Displays the changelog for a specific version of a mod").SetDeferred()
 	commands = append(commands, changelog)
 	changelog.AddOption("mod", "Mod name").SetAutocomplete()
-	changelog.AddOption("version", "Mod version").SetAutocomplete()
-	changelog.Handler = func(i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+	changelog.AddOption("version", "Mod version").SetOptional().SetAutocomplete()
+	changelog.AddOption("from", "Show changes after this version").SetOptional().SetAutocomplete()
+	changelog.AddOption("to", "Show changes up to this version").SetOptional().SetAutocomplete()
+	changelog.Handler = func(m Messenger, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
 		options := MapOptions(data.Options)
 
 		switch i.Type {
@@ -140,317 +186,976 @@ func InitCommands() ([]*discordgo.ApplicationCommand, map[string]func(*discordgo
 			value := options["mod"].StringValue()
 			mod := mods[value]
 			if mod == nil {
-				RespondError(i, "Invalid Mod Name", fmt.Sprintf("The mod %s was not found.", value))
+				RespondError(m, i, "Invalid Mod Name", fmt.Sprintf("The mod %s was not found.", value))
+				return
+			}
+			if options["version"] != nil && options["to"] != nil {
+				RespondError(m, i, "Invalid Options", "Use either `version` for a single release or `from` and `to` for a range, not both `version` and `to`.")
 				return
 			}
 
 			fullMod, err := mod.Request(true)
 			if err != nil {
-				RespondDefaultError(i)
+				RespondDefaultError(m, i)
 				return
 			}
 
-			var version string
+			version := mod.LatestRelease.Version
 			if options["version"] != nil {
 				version = options["version"].StringValue()
-			} else {
-				version = mod.LatestRelease.Version
+			}
+			if options["to"] != nil {
+				version = options["to"].StringValue()
 			}
 
 			release := fullMod.GetRelease(version)
 			if release == nil {
-				RespondError(i, "Invalid Version", fmt.Sprintf("%s does not have a release for version `%s`.\nPlease use the autocomplete list for a valid version.", mod.Title, version))
+				RespondError(m, i, "Invalid Version", fmt.Sprintf("%s does not have a release for version `%s`.\nPlease use the autocomplete list for a valid version.", mod.Title, version))
 				return
 			}
 
-			description := fullMod.FormatChangelog(version)
-			if description == "" {
-				description = fmt.Sprintf("No changelog for version %s", version)
+			isRange := options["from"] != nil || options["to"] != nil
+			var from string
+			if options["from"] != nil {
+				from = options["from"].StringValue()
+				if fullMod.GetRelease(from) == nil {
+					RespondError(m, i, "Invalid Version", fmt.Sprintf("%s does not have a release for version `%s`.\nPlease use the autocomplete list for a valid version.", mod.Title, from))
+					return
+				}
+				if CompareVersions(from, version) >= 0 {
+					RespondError(m, i, "Invalid Range", fmt.Sprintf("`%s` must be older than `%s`.", from, version))
+					return
+				}
 			}
 
-			RespondEmbed(i, discordgo.MessageEmbed{
-				Title:       fmt.Sprintf("%s %s", Truncate(mod.Title, 256-len(version)), version),
-				URL:         mod.URL(),
-				Description: description,
-				Thumbnail:   &discordgo.MessageEmbedThumbnail{URL: fullMod.GetThumbnail()},
-				Color:       colors.Gold,
-				Fields: []*discordgo.MessageEmbedField{{
-					Value:  fmt.Sprintf("**Author:** [%s](https://mods.factorio.com/user/%s)", mod.Owner, mod.Owner),
-					Inline: true,
-				}, {
-					Value:  fmt.Sprintf("**Released:** %s", Timestamp(release.ReleasedAt)),
-					Inline: true,
-				}},
-			})
+			args := []string{mod.Name, version, from, Ternary(isRange, "range", "")}
+			RespondPages(m, i, "changelog", args, ChangelogPages(fullMod, *release, from, isRange), 0)
+		case discordgo.InteractionApplicationCommandAutocomplete:
+			RespondChoices(m, i, ModVersionChoices(data.Options, options))
+		}
+	}
+
+	dependencies := NewCommand("dependencies", "Lists the dependencies of a mod").SetDeferred()
+	commands = append(commands, dependencies)
+	dependencies.AddOption("mod", "Mod name").SetAutocomplete()
+	dependencies.AddOption("version", "Mod version").SetOptional().SetAutocomplete()
+	dependencies.Handler = func(m Messenger, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+		options := MapOptions(data.Options)
+
+		switch i.Type {
+		case discordgo.InteractionApplicationCommand:
+			value := options["mod"].StringValue()
+			mod := mods[value]
+			if mod == nil {
+				RespondError(m, i, "Invalid Mod Name", fmt.Sprintf("The mod %s was not found.", value))
+				return
+			}
+
+			fullMod, err := mod.Request(true)
+			if err != nil {
+				RespondDefaultError(m, i)
+				return
+			}
+
+			version := mod.LatestRelease.Version
+			if options["version"] != nil {
+				version = options["version"].StringValue()
+			}
+			release := fullMod.GetRelease(version)
+			if release == nil {
+				RespondError(m, i, "Invalid Version", fmt.Sprintf("%s does not have a release for version `%s`.\nPlease use the autocomplete list for a valid version.", mod.Title, version))
+				return
+			}
+
+			RespondPages(m, i, "dependencies", []string{mod.Name, release.Version}, DependenciesPages(fullMod, *release), 0)
+		case discordgo.InteractionApplicationCommandAutocomplete:
+			RespondChoices(m, i, ModVersionChoices(data.Options, options))
+		}
+	}
+
+	dependentsCommand := NewCommand("dependents", "Lists the mods that depend on a mod")
+	commands = append(commands, dependentsCommand)
+	dependentsCommand.AddOption("mod", "Mod name").SetAutocomplete()
+	dependentsCommand.AddOption("page", "Starting page").SetOptional().SetType("int")
+	dependentsCommand.Handler = func(m Messenger, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+		options := MapOptions(data.Options)
+
+		switch i.Type {
+		case discordgo.InteractionApplicationCommand:
+			value := options["mod"].StringValue()
+			mod := mods[value]
+			if mod == nil {
+				RespondError(m, i, "Invalid Mod Name", fmt.Sprintf("The mod %s was not found.", value))
+				return
+			}
+
+			page := 1
+			if options["page"] != nil {
+				page = int(options["page"].IntValue())
+			}
+			RespondPages(m, i, "dependents", []string{mod.Name}, DependentsPages(mod), page-1)
 		case discordgo.InteractionApplicationCommandAutocomplete:
 			focused := FocusedOption(data.Options)
-			var choices []*discordgo.ApplicationCommandOptionChoice
+			modArr := ModAutocomplete(versions["all"], focused.StringValue())
+			RespondChoices(m, i, ModChoices(VersionSort(modArr)))
+		}
+	}
+
+	modpack := NewCommand("modpack", "Checks a modpack against the mod portal").SetDeferred()
+	commands = append(commands, modpack)
+	check := modpack.AddOption("check", "Checks a mod list for missing, outdated and conflicting mods")
+	check.AddOption("file", "mod-list.json, save .zip or zipped mods folder").SetType("file")
+	check.AddOption("factorio-version", "Target Factorio version").SetOptional().SetAutocomplete()
+	modpack.Handler = func(m Messenger, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+		subCommand := data.Options[0]
+		options := MapOptions(subCommand.Options)
+
+		switch i.Type {
+		case discordgo.InteractionApplicationCommand:
+			version := GuildVersion(i.GuildID)
+			if options["factorio-version"] != nil {
+				version = options["factorio-version"].StringValue()
+			}
+
+			list, ok := AttachmentModList(m, i, data, options["file"])
+			if !ok {
+				return
+			}
+			// The report depends on the uploaded file, so its pages are kept
+			// rather than rebuilt on every click.
+			RespondStoredPages(m, i, ModpackPages(CheckModpack(list, version), version), 0)
+		case discordgo.InteractionApplicationCommandAutocomplete:
+			value := options["factorio-version"].StringValue()
+			var choices []string
+			for _, version := range FactorioVersions() {
+				if strings.HasPrefix(version, value) {
+					choices = append(choices, version)
+				}
+			}
+			RespondChoices(m, i, StringChoices(choices))
+		}
+	}
 
+	track := NewCommand("track", "Adds mods to the list of tracked mods").SetPermission(discordgo.PermissionManageServer).SetDeferred()
+	commands = append(commands, track)
+	track.AddOption("mod", "Adds a mod to the list of tracked mods").AddOption("mod", "Mod name").SetAutocomplete()
+	track.AddOption("author", "Adds an author to the list of tracked authors").AddOption("author", "Author name").SetAutocomplete()
+	file := track.AddOption("file", "Adds enabled mods from a mod list, save or mods folder to the list of tracked mods")
+	file.AddOption("mod-list", "mod-list.json, save .zip or zipped mods folder").SetType("file")
+	track.AddOption("all", "Sets whether all mods should be tracked").AddOption("enabled", "enabled").SetType("bool")
+	track.AddOption("enabled", "Sets whether update messages should be sent").AddOption("enabled", "enabled").SetType("bool")
+	track.AddOption("changelogs", "Sets whether changelogs should be shown for mod updates").AddOption("enabled", "enabled").SetType("bool")
+	track.AddOption("set_channel", "Sets the channel for mod updates, creating the route if needed").AddOption("channel", "The channel to send mod updates in").SetType("channel")
+	webhook := track.AddOption("webhook", "Sets whether updates are posted through a webhook using each mod's name and thumbnail")
+	webhook.AddOption("enabled", "enabled").SetType("bool")
+	webhook.AddOption("url", "Existing webhook URL to use instead of creating one").SetOptional()
+	trackVersion := track.AddOption("version", "Sets whether updates for a Factorio version should be sent")
+	trackVersion.AddOption("version", "Factorio version").SetAutocomplete()
+	trackVersion.AddOption("enabled", "enabled").SetType("bool")
+	category := track.AddOption("category", "Filters which categories and tags are tracked when tracking all mods")
+	category.AddOption("include", "Only track mods with this category or tag").AddOption("category", "Category or tag").SetAutocomplete()
+	category.AddOption("exclude", "Never track mods with this category or tag").AddOption("category", "Category or tag").SetAutocomplete()
+	category.AddOption("remove", "Removes a category or tag from the filters").AddOption("category", "Category or tag").SetAutocomplete()
+	track.AddOption("list", "Lists the tracked mods and authors").SetType("command")
+	track.AddOption("test", "Sends a test message to the mod update channel").SetType("command")
+	for _, option := range track.Options {
+		if option.Name != "enabled" {
+			AddRouteOption(option)
+		}
+	}
+	trackPing := track.AddOption("ping", "Pings a role when a mod or any mod by an author updates")
+	trackPing.AddOption("role", "Role to ping").SetType("role")
+	trackPing.AddOption("mod", "Mod name").SetOptional().SetAutocomplete()
+	trackPing.AddOption("author", "Author name").SetOptional().SetAutocomplete()
+	track.Handler = func(m Messenger, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+		switch i.Type {
+		case discordgo.InteractionApplicationCommand:
+			subCommand := data.Options[0]
+			routeName := RouteName(SubCommand(data.Options))
+			switch subCommand.Name {
+			case "mod":
+				name := subCommand.Options[0].StringValue()
+				if mods[name] == nil {
+					RespondError(m, i, "Invalid Mod Name", fmt.Sprintf("The mod `%s` does not exist. Please use the autocomplete list for a valid mod.", name))
+					return
+				}
+				ok := UpdateRoute(m, i, routeName, func(route *Route) {
+					route.TrackedMods[name] = true
+					route.TrackAll = false
+				})
+				if ok {
+					RespondSuccess(m, i, fmt.Sprintf("Added `%s` to tracked mods", name))
+				}
+			case "author":
+				name := subCommand.Options[0].StringValue()
+				author := authors[name]
+				if author == nil {
+					RespondError(m, i, "Invalid Author Name", fmt.Sprintf("The author `%s` does not exist. Please use the autocomplete list for a valid author.", name))
+					return
+				}
+				ok := UpdateRoute(m, i, routeName, func(route *Route) {
+					route.TrackedAuthors[name] = true
+					route.TrackAll = false
+					for _, mod := range author.Mods {
+						route.TrackedMods[mod.Name] = true
+					}
+				})
+				if ok {
+					RespondSuccess(m, i, fmt.Sprintf("Added `%s` to tracked authors.", name))
+				}
+			case "file":
+				list, ok := AttachmentModList(m, i, data, subCommand.Options[0])
+				if !ok {
+					return
+				}
+
+				ok = UpdateRoute(m, i, routeName, func(route *Route) {
+					for _, mod := range list.Mods {
+						if mod.Enabled && !vanillaMods[mod.Name] {
+							route.TrackedMods[mod.Name] = true
+						}
+					}
+				})
+				if ok {
+					RespondSuccess(m, i, "Added enabled mods to the tracked list")
+				}
+			case "all":
+				value := subCommand.Options[0].BoolValue()
+				ok := UpdateRoute(m, i, routeName, func(route *Route) {
+					route.TrackAll = value
+				})
+				if ok {
+					RespondSuccess(m, i, fmt.Sprintf("%s tracking of all mods", Ternary(value, "Enabled", "Disabled")))
+				}
+			case "changelogs":
+				value := subCommand.Options[0].BoolValue()
+				ok := UpdateRoute(m, i, routeName, func(route *Route) {
+					route.Changelogs = value
+				})
+				if ok {
+					RespondSuccess(m, i, fmt.Sprintf("%s changelog updates", Ternary(value, "Enabled", "Disabled")))
+				}
+			case "enabled":
+				value := subCommand.Options[0].BoolValue()
+				noChannel := false
+				ok := UpdateGuild(m, i, func(guildData *GuildData) {
+					if value && !guildData.HasChannel() {
+						noChannel = true
+						return
+					}
+					guildData.TrackEnabled = value
+				})
+				if !ok {
+					return
+				}
+				if noChannel {
+					RespondError(m, i, "Unset Update Channel", "Please set an update channel with `/track set_channel` before enabling mod updates.")
+					return
+				}
+				RespondSuccess(m, i, fmt.Sprintf("%s mod update messages", Ternary(value, "Enabled", "Disabled")))
+			case "set_channel":
+				channel := data.Resolved.Channels[subCommand.Options[0].Value.(string)]
+				if channel.Type != 0 && channel.Type != 5 {
+					RespondError(m, i, "Invalid Channel Type", fmt.Sprintf("<#%s> is not a text channel.", channel.ID))
+					return
+				}
+				permissions, err := m.ChannelPermissions(channel.ID)
+				if err != nil {
+					RespondDefaultError(m, i)
+					return
+				}
+				if permissions&0x400 == 0 {
+					RespondError(m, i, "Invalid Permissions", fmt.Sprintf("Cannot view channel <#%s>", channel.ID))
+					return
+				}
+				if permissions&0x800 == 0 {
+					RespondError(m, i, "Invalid Permissions", fmt.Sprintf("Cannot send messages in <#%s>", channel.ID))
+					return
+				}
+				if permissions&0x4000 == 0 {
+					RespondError(m, i, "Invalid Permissions", fmt.Sprintf("Cannot embed links in <#%s>", channel.ID))
+					return
+				}
+
+				var oldWebhook *Webhook
+				ok := UpdateGuild(m, i, func(guildData *GuildData) {
+					route := guildData.CreateRoute(routeName)
+					if route.Channel != channel.ID {
+						oldWebhook = route.Webhook
+						route.Webhook = nil
+					}
+					route.Channel = channel.ID
+				})
+				if !ok {
+					return
+				}
+				out := fmt.Sprintf("Update channel%s set to <#%s>", RouteSuffix(routeName), channel.ID)
+				if oldWebhook != nil {
+					if oldWebhook.Created {
+						m.DeleteWebhook(oldWebhook.ID)
+					}
+					out += "\nThe webhook for the previous channel was removed, use `/track webhook` to set up a new one."
+				}
+				RespondSuccess(m, i, out)
+			case "webhook":
+				options := MapOptions(subCommand.Options)
+				value := options["enabled"].BoolValue()
+				guildData, err := guildStore.Get(i.GuildID)
+				if err != nil {
+					RespondDefaultError(m, i)
+					return
+				}
+				route := guildData.GetRoute(routeName)
+				if route == nil {
+					RespondRouteError(m, i, routeName)
+					return
+				}
+
+				if !value {
+					var oldWebhook *Webhook
+					ok := UpdateRoute(m, i, routeName, func(route *Route) {
+						oldWebhook = route.Webhook
+						route.Webhook = nil
+					})
+					if !ok {
+						return
+					}
+					if oldWebhook != nil && oldWebhook.Created {
+						m.DeleteWebhook(oldWebhook.ID)
+					}
+					RespondSuccess(m, i, "Disabled webhook updates")
+					return
+				}
+
+				var newWebhook *Webhook
+				channelID := route.Channel
+				if options["url"] != nil {
+					newWebhook, err = ParseWebhookURL(options["url"].StringValue())
+					if err != nil {
+						RespondError(m, i, "Invalid Webhook", "Please provide a webhook URL copied from the channel's integration settings.")
+						return
+					}
+					hook, err := m.GetWebhook(newWebhook.ID, newWebhook.Token)
+					if err != nil || hook.GuildID != i.GuildID {
+						RespondError(m, i, "Invalid Webhook", "The webhook does not exist or belongs to another server.")
+						return
+					}
+					channelID = hook.ChannelID
+				} else {
+					if channelID == "" {
+						RespondError(m, i, "Unset Update Channel", "Please set an update channel with `/track set_channel` before enabling webhook updates.")
+						return
+					}
+					permissions, err := m.ChannelPermissions(channelID)
+					if err != nil {
+						RespondDefaultError(m, i)
+						return
+					}
+					if permissions&discordgo.PermissionManageWebhooks == 0 {
+						RespondError(m, i, "Invalid Permissions", fmt.Sprintf("Cannot manage webhooks in <#%s>", channelID))
+						return
+					}
+					hook, err := m.CreateWebhook(channelID, "Mod Portal Updates")
+					if err != nil {
+						RespondError(m, i, "Failed to create webhook", "```"+err.Error()+"```")
+						return
+					}
+					newWebhook = &Webhook{ID: hook.ID, Token: hook.Token, Created: true}
+				}
+
+				var oldWebhook *Webhook
+				ok := UpdateRoute(m, i, routeName, func(route *Route) {
+					oldWebhook = route.Webhook
+					route.Webhook = newWebhook
+					route.Channel = channelID
+				})
+				if !ok {
+					return
+				}
+				if oldWebhook != nil && oldWebhook.Created && oldWebhook.ID != newWebhook.ID {
+					m.DeleteWebhook(oldWebhook.ID)
+				}
+				RespondSuccess(m, i, fmt.Sprintf("Updates%s will be posted through a webhook in <#%s>", RouteSuffix(routeName), channelID))
+			case "version":
+				options := MapOptions(subCommand.Options)
+				version := options["version"].StringValue()
+				value := options["enabled"].BoolValue()
+				if !slices.Contains(FactorioVersions(), version) {
+					RespondError(m, i, "Invalid Version", fmt.Sprintf("No mods exist for Factorio version `%s`. Please use the autocomplete list for a valid version.", version))
+					return
+				}
+				ok := UpdateRoute(m, i, routeName, func(route *Route) {
+					if value {
+						route.Versions[version] = true
+					} else {
+						delete(route.Versions, version)
+					}
+				})
+				if ok {
+					RespondSuccess(m, i, fmt.Sprintf("%s updates for Factorio %s", Ternary(value, "Enabled", "Disabled"), version))
+				}
+			case "category":
+				action := subCommand.Options[0]
+				name := action.Options[0].StringValue()
+				if action.Name != "remove" && categories[name] == 0 {
+					RespondError(m, i, "Invalid Category", fmt.Sprintf("No mods have the category or tag `%s`. Please use the autocomplete list for a valid category.", name))
+					return
+				}
+				var trackAll bool
+				ok := UpdateRoute(m, i, routeName, func(route *Route) {
+					delete(route.IncludeCategories, name)
+					delete(route.ExcludeCategories, name)
+					switch action.Name {
+					case "include":
+						route.IncludeCategories[name] = true
+					case "exclude":
+						route.ExcludeCategories[name] = true
+					}
+					trackAll = route.TrackAll
+				})
+				if !ok {
+					return
+				}
+				var out string
+				switch action.Name {
+				case "include":
+					out = fmt.Sprintf("Added `%s` to included categories", name)
+				case "exclude":
+					out = fmt.Sprintf("Added `%s` to excluded categories", name)
+				case "remove":
+					out = fmt.Sprintf("Removed `%s` from category filters", name)
+				}
+				if !trackAll {
+					out += "\nCategory filters only apply while tracking all mods, enable it with `/track all`."
+				}
+				RespondSuccess(m, i, out)
+			case "ping":
+				options := MapOptions(subCommand.Options)
+				role := options["role"].RoleValue(nil, "")
+				modName, authorName, ok := PingTarget(m, i, options)
+				if !ok {
+					return
+				}
+				added := false
+				ok = UpdateGuild(m, i, func(guildData *GuildData) {
+					if modName != "" {
+						added = AddPing(guildData.ModPings, modName, role.ID) || added
+					}
+					if authorName != "" {
+						added = AddPing(guildData.AuthorPings, authorName, role.ID) || added
+					}
+				})
+				if !ok {
+					return
+				}
+				if !added {
+					RespondSuccess(m, i, fmt.Sprintf("<@&%s> is already pinged for %s", role.ID, PingTargetName(modName, authorName)))
+					return
+				}
+				RespondSuccess(m, i, fmt.Sprintf("<@&%s> will be pinged for updates to %s", role.ID, PingTargetName(modName, authorName)))
+			case "list":
+				guildData, err := guildStore.Get(i.GuildID)
+				if err != nil {
+					RespondDefaultError(m, i)
+					return
+				}
+				route := guildData.GetRoute(routeName)
+				if route == nil {
+					RespondRouteError(m, i, routeName)
+					return
+				}
+
+				RespondPages(m, i, "tracklist", []string{routeName}, TrackListPages(guildData, routeName, route), 0)
+			case "test":
+				guildData, err := guildStore.Get(i.GuildID)
+				if err != nil {
+					RespondDefaultError(m, i)
+					return
+				}
+				route := guildData.GetRoute(routeName)
+				if route == nil {
+					RespondRouteError(m, i, routeName)
+					return
+				}
+				err = SendToRoute(m, i.GuildID, routeName, route, &discordgo.MessageSend{
+					Embeds: []*discordgo.MessageEmbed{{
+						Description: "Mod Update Test",
+						Color:       colors.Blue,
+					}},
+				}, "", "")
+				if err != nil {
+					RespondError(m, i, "Failed to send test mod update", "```"+err.Error()+"```")
+				} else {
+					RespondSuccess(m, i, "Mod update test successful")
+				}
+			}
+		case discordgo.InteractionApplicationCommandAutocomplete:
+			var choices []*discordgo.ApplicationCommandOptionChoice
+			focused := FocusedOption(SubCommand(data.Options).Options)
 			switch focused.Name {
+			case "category":
+				choices = StringChoices(CategoryAutocomplete(focused.StringValue()))
 			case "mod":
-				value := focused.StringValue()
-				modArr := versions["all"]
-				modArr = ModAutocomplete(modArr, value)
+				modArr := ModAutocomplete(versions["all"], focused.StringValue())
 				modArr = VersionSort(modArr)
 				choices = ModChoices(modArr)
+			case "author":
+				authorArr := AuthorAutocomplete(focused.StringValue())
+				choices = AuthorChoices(authorArr)
 			case "version":
-				name := options["mod"]
-				if name == nil {
-					break
+				choices = StringChoices(FactorioVersions())
+			case "route":
+				choices = RouteChoices(i.GuildID, focused.StringValue())
+			}
+			RespondChoices(m, i, choices)
+		}
+	}
+
+	untrack := NewCommand("untrack", "Removes mods from the list of tracked mods").SetPermission(discordgo.PermissionManageServer)
+	commands = append(commands, untrack)
+	untrack.AddOption("mod", "Removes a mod from the list of tracked mods").AddOption("mod", "Mod name").SetAutocomplete()
+	untrack.AddOption("author", "Removes an author from the list of tracked authors").AddOption("author", "Author name").SetAutocomplete()
+	untrack.AddOption("all", "Removes all mods and authors from both tracked lists").SetType("command")
+	for _, option := range untrack.Options {
+		AddRouteOption(option)
+	}
+	untrack.AddOption("route", "Deletes an update route").AddOption("route", "Route name").SetAutocomplete()
+	untrackPing := untrack.AddOption("ping", "Stops pinging a role for a mod or author")
+	untrackPing.AddOption("role", "Role to stop pinging").SetType("role")
+	untrackPing.AddOption("mod", "Mod name").SetOptional().SetAutocomplete()
+	untrackPing.AddOption("author", "Author name").SetOptional().SetAutocomplete()
+	untrack.Handler = func(m Messenger, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+		switch i.Type {
+		case discordgo.InteractionApplicationCommand:
+			subCommand := data.Options[0]
+			routeName := RouteName(subCommand)
+			switch subCommand.Name {
+			case "mod":
+				name := subCommand.Options[0].StringValue()
+				if mods[name] == nil {
+					RespondError(m, i, "Invalid Mod Name", fmt.Sprintf("The mod `%s` does not exist. Please use the autocomplete list for a valid mod.", name))
+					return
 				}
 
-				mod := mods[name.StringValue()]
-				if mod == nil {
-					break
+				ok := UpdateRoute(m, i, routeName, func(route *Route) {
+					delete(route.TrackedMods, name)
+				})
+				if ok {
+					RespondSuccess(m, i, fmt.Sprintf("Removed `%s` from tracked mods", name))
+				}
+			case "author":
+				name := subCommand.Options[0].StringValue()
+				author := authors[name]
+				if author == nil {
+					RespondError(m, i, "Invalid Author Name", fmt.Sprintf("The author `%s` does not exist. Please use the autocomplete for a valid name.", name))
+					return
 				}
 
-				fullMod, err := mod.Request(true)
-				if err != nil {
-					break
+				ok := UpdateRoute(m, i, routeName, func(route *Route) {
+					for _, mod := range author.Mods {
+						delete(route.TrackedMods, mod.Name)
+					}
+					delete(route.TrackedAuthors, name)
+				})
+				if ok {
+					RespondSuccess(m, i, fmt.Sprintf("Removed `%s` from tracked authors", name))
+				}
+			case "all":
+				ok := UpdateRoute(m, i, routeName, func(route *Route) {
+					route.TrackedMods = map[string]bool{}
+					route.TrackedAuthors = map[string]bool{}
+				})
+				if ok {
+					RespondSuccess(m, i, "Removed all mods and authors from the tracked lists")
+				}
+			case "route":
+				if routeName == "" {
+					RespondError(m, i, "Invalid Route", "The main route cannot be deleted.")
+					return
+				}
+				var oldRoute *Route
+				ok := UpdateGuild(m, i, func(guildData *GuildData) {
+					oldRoute = guildData.Routes[routeName]
+					delete(guildData.Routes, routeName)
+				})
+				if !ok {
+					return
+				}
+				if oldRoute == nil {
+					RespondRouteError(m, i, routeName)
+					return
+				}
+				if oldRoute.Webhook != nil && oldRoute.Webhook.Created {
+					m.DeleteWebhook(oldRoute.Webhook.ID)
+				}
+				RespondSuccess(m, i, fmt.Sprintf("Deleted route `%s`", routeName))
+			case "ping":
+				options := MapOptions(subCommand.Options)
+				role := options["role"].RoleValue(nil, "")
+				modName, authorName, ok := PingTarget(m, i, options)
+				if !ok {
+					return
 				}
+				removed := false
+				ok = UpdateGuild(m, i, func(guildData *GuildData) {
+					if modName != "" {
+						removed = RemovePing(guildData.ModPings, modName, role.ID) || removed
+					}
+					if authorName != "" {
+						removed = RemovePing(guildData.AuthorPings, authorName, role.ID) || removed
+					}
+				})
+				if !ok {
+					return
+				}
+				if !removed {
+					RespondSuccess(m, i, fmt.Sprintf("<@&%s> is not pinged for %s", role.ID, PingTargetName(modName, authorName)))
+					return
+				}
+				RespondSuccess(m, i, fmt.Sprintf("<@&%s> will no longer be pinged for updates to %s", role.ID, PingTargetName(modName, authorName)))
+			}
+		case discordgo.InteractionApplicationCommandAutocomplete:
+			var choices []*discordgo.ApplicationCommandOptionChoice
+			subCommand := data.Options[0]
+			focused := FocusedOption(subCommand.Options)
+			if focused.Name == "route" {
+				RespondChoices(m, i, RouteChoices(i.GuildID, focused.StringValue()))
+				return
+			}
+			guildData, err := guildStore.Get(i.GuildID)
+			if err != nil {
+				RespondChoices(m, i, choices)
+				return
+			}
+			if subCommand.Name == "ping" {
+				var names []string
+				switch focused.Name {
+				case "mod":
+					names = SortedKeys(guildData.ModPings)
+				case "author":
+					names = SortedKeys(guildData.AuthorPings)
+				}
+				for _, name := range names {
+					if strings.Contains(name, focused.StringValue()) && len(choices) < 25 {
+						choices = append(choices, Choice(name, name))
+					}
+				}
+				RespondChoices(m, i, choices)
+				return
+			}
+			route := guildData.GetRoute(RouteName(subCommand))
+			if route == nil {
+				RespondChoices(m, i, choices)
+				return
+			}
+			switch focused.Name {
+			case "mod":
+				var modArr []*Mod
+				for name := range route.TrackedMods {
+					if mod := mods[name]; mod != nil {
+						modArr = append(modArr, mod)
+					}
+				}
+				choices = ModChoices(ModAutocomplete(modArr, focused.StringValue()))
+			case "author":
+				authorArr := AuthorAutocompleteList(route.TrackedAuthors, focused.StringValue())
+				choices = AuthorChoices(authorArr)
+			}
+			RespondChoices(m, i, choices)
+		}
+	}
+
+	subscribe := NewCommand("subscribe", "Get notified when a mod updates").SetEphemeral()
+	commands = append(commands, subscribe)
+	subscribeMod := subscribe.AddOption("mod", "Subscribes to updates of a mod")
+	subscribeMod.AddOption("mod", "Mod name").SetAutocomplete()
+	subscribeMod.AddOption("notify", "dm: direct message (default), mention: ping in this server's update channel").SetOptional().SetChoices("dm", "mention")
+	subscribe.AddOption("author", "Subscribes to updates of every mod by an author").AddOption("author", "Author name").SetAutocomplete()
+	subscribe.Handler = func(m Messenger, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+		switch i.Type {
+		case discordgo.InteractionApplicationCommand:
+			subCommand := data.Options[0]
+			options := MapOptions(subCommand.Options)
+			userID := InteractionUserID(i)
+			switch subCommand.Name {
+			case "mod":
+				name := options["mod"].StringValue()
+				mod := mods[name]
+				if mod == nil {
+					RespondError(m, i, "Invalid Mod Name", fmt.Sprintf("The mod `%s` does not exist. Please use the autocomplete list for a valid mod.", name))
+					return
+				}
+				if options["notify"] != nil && options["notify"].StringValue() == "mention" {
+					if i.GuildID == "" {
+						RespondError(m, i, "Server Only", "Mentions can only be set up from within a server.")
+						return
+					}
+					guildData, err := guildStore.Get(i.GuildID)
+					if err != nil {
+						slog.Error("Could not read guild", "guild", i.GuildID, "err", err)
+						RespondDefaultError(m, i)
+						return
+					}
+					if !guildData.Tracks(*mod) {
+						RespondError(m, i, "Mod Not Tracked", fmt.Sprintf("No update channel in this server announces %s, so there is nowhere to mention you. Ask a server admin to track it, or subscribe by direct message instead.", mod.Title))
+						return
+					}
+					ok := UpdateGuild(m, i, func(guildData *GuildData) {
+						if guildData.Subscribers[name] == nil {
+							guildData.Subscribers[name] = map[string]bool{}
+						}
+						guildData.Subscribers[name][userID] = true
+					})
+					if ok {
+						RespondSuccess(m, i, fmt.Sprintf("You will be mentioned in this server when %s updates", mod.Title))
+					}
+					return
+				}
+				ok := UpdateUser(m, i, func(userData *UserData) {
+					userData.Mods[name] = true
+				})
+				if ok {
+					RespondSuccess(m, i, fmt.Sprintf("You will be sent a direct message when %s updates", mod.Title))
+				}
+			case "author":
+				name := options["author"].StringValue()
+				if authors[name] == nil {
+					RespondError(m, i, "Invalid Author Name", fmt.Sprintf("The author `%s` does not exist. Please use the autocomplete list for a valid author.", name))
+					return
+				}
+				ok := UpdateUser(m, i, func(userData *UserData) {
+					userData.Authors[name] = true
+				})
+				if ok {
+					RespondSuccess(m, i, fmt.Sprintf("You will be sent a direct message when any mod by %s updates", name))
+				}
+			}
+		case discordgo.InteractionApplicationCommandAutocomplete:
+			var choices []*discordgo.ApplicationCommandOptionChoice
+			focused := FocusedOption(data.Options[0].Options)
+			switch focused.Name {
+			case "mod":
+				modArr := ModAutocomplete(versions["all"], focused.StringValue())
+				choices = ModChoices(VersionSort(modArr))
+			case "author":
+				choices = AuthorChoices(AuthorAutocomplete(focused.StringValue()))
+			}
+			RespondChoices(m, i, choices)
+		}
+	}
 
-				var versionArr []string
-				for i := 1; i <= len(fullMod.Releases) && i <= 25; i++ {
-					versionArr = append(versionArr, fullMod.Releases[len(fullMod.Releases)-i].Version)
+	unsubscribe := NewCommand("unsubscribe", "Stop getting notified when a mod updates").SetEphemeral()
+	commands = append(commands, unsubscribe)
+	unsubscribe.AddOption("mod", "Unsubscribes from a mod").AddOption("mod", "Mod name").SetAutocomplete()
+	unsubscribe.AddOption("author", "Unsubscribes from an author").AddOption("author", "Author name").SetAutocomplete()
+	unsubscribe.AddOption("all", "Removes all of your direct message subscriptions").SetType("command")
+	unsubscribe.Handler = func(m Messenger, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+		userID := InteractionUserID(i)
+		switch i.Type {
+		case discordgo.InteractionApplicationCommand:
+			subCommand := data.Options[0]
+			switch subCommand.Name {
+			case "mod":
+				name := subCommand.Options[0].StringValue()
+				ok := UpdateUser(m, i, func(userData *UserData) {
+					delete(userData.Mods, name)
+				})
+				if ok && i.GuildID != "" {
+					ok = UpdateGuild(m, i, func(guildData *GuildData) {
+						delete(guildData.Subscribers[name], userID)
+						if len(guildData.Subscribers[name]) == 0 {
+							delete(guildData.Subscribers, name)
+						}
+					})
+				}
+				if ok {
+					RespondSuccess(m, i, fmt.Sprintf("Unsubscribed from `%s`", name))
+				}
+			case "author":
+				name := subCommand.Options[0].StringValue()
+				ok := UpdateUser(m, i, func(userData *UserData) {
+					delete(userData.Authors, name)
+				})
+				if ok {
+					RespondSuccess(m, i, fmt.Sprintf("Unsubscribed from `%s`", name))
+				}
+			case "all":
+				if err := userStore.Delete(userID); err != nil {
+					slog.Error("Could not delete user", "user", userID, "err", err)
+					RespondDefaultError(m, i)
+					return
+				}
+				RespondSuccess(m, i, "Removed all of your direct message subscriptions")
+			}
+		case discordgo.InteractionApplicationCommandAutocomplete:
+			var choices []*discordgo.ApplicationCommandOptionChoice
+			focused := FocusedOption(data.Options[0].Options)
+			userData, err := userStore.Get(userID)
+			if err != nil {
+				RespondChoices(m, i, choices)
+				return
+			}
+			switch focused.Name {
+			case "mod":
+				names := userData.Mods
+				if i.GuildID != "" {
+					if guildData, err := guildStore.Get(i.GuildID); err == nil {
+						for name, users := range guildData.Subscribers {
+							if users[userID] {
+								names[name] = true
+							}
+						}
+					}
+				}
+				var modArr []*Mod
+				for name := range names {
+					if mod := mods[name]; mod != nil {
+						modArr = append(modArr, mod)
+					}
 				}
-
-				choices = StringChoices(versionArr)
+				choices = ModChoices(ModAutocomplete(modArr, focused.StringValue()))
+			case "author":
+				choices = AuthorChoices(AuthorAutocompleteList(userData.Authors, focused.StringValue()))
 			}
+			RespondChoices(m, i, choices)
+		}
+	}
 
-			RespondChoices(i, choices)
+	subscriptions := NewCommand("subscriptions", "Shows your mod update subscriptions").SetEphemeral()
+	commands = append(commands, subscriptions)
+	subscriptions.AddOption("list", "Lists the mods and authors you are subscribed to").SetType("command")
+	subscriptions.Handler = func(m Messenger, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+		userID := InteractionUserID(i)
+		pages, err := SubscriptionsPages(userID, i.GuildID)
+		if err != nil {
+			slog.Error("Could not list subscriptions", "user", userID, "err", err)
+			RespondDefaultError(m, i)
+			return
 		}
+		RespondPages(m, i, "subscriptions", nil, pages, 0)
 	}
 
-	track := NewCommand("track", "Adds mods to the list of tracked mods").SetPermission(discordgo.PermissionManageServer)
-	commands = append(commands, track)
-	track.AddOption("mod", "Adds a mod to the list of tracked mods").AddOption("mod", "Mod name").SetAutocomplete()
-	track.AddOption("author", "Adds an author to the list of tracked authors").AddOption("author", "Author name").SetAutocomplete()
-	file := track.AddOption("file", "Adds enabled mods from a mod-list.json to the list of tracked mods")
-	file.AddOption("mod-list", "mod-list.json file").SetType("file")
-	track.AddOption("all", "Sets whether all mods should be tracked").AddOption("enabled", "enabled").SetType("bool")
-	track.AddOption("enabled", "Sets whether update messages should be sent").AddOption("enabled", "enabled").SetType("bool")
-	track.AddOption("changelogs", "Sets whether changelogs should be shown for mod updates").AddOption("enabled", "enabled").SetType("bool")
-	track.AddOption("set_channel", "Sets the channel for mod updates").AddOption("channel", "The channel to send mod updates in").SetType("channel")
-	track.AddOption("list", "Lists the tracked mods and authors").SetType("command")
-	track.AddOption("test", "Sends a test message to the mod update channel").SetType("command")
-	track.Handler = func(i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+	settings := NewCommand("settings", "Changes bot settings for this server").SetPermission(discordgo.PermissionManageServer)
+	commands = append(commands, settings)
+	settings.AddOption("version", "Sets the default Factorio version used by /mod").AddOption("version", "Factorio version, or \"default\" to reset").SetAutocomplete()
+	delivery := settings.AddOption("delivery", "Sets how update messages are delivered")
+	delivery.AddOption("mode", "immediate: one message per release, batch: one message per check, digest: scheduled summary").SetChoices(DeliveryImmediate, DeliveryBatch, DeliveryDigest)
+	delivery.AddOption("schedule", "Digest interval").SetOptional().SetChoices("hourly", "daily", "weekly")
+	delivery.AddOption("hour", "Digest hour in UTC (0-23)").SetOptional().SetType("int")
+	delivery.AddOption("weekday", "Digest weekday for weekly digests").SetOptional().SetChoices("Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday")
+	settings.Handler = func(m Messenger, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
 		switch i.Type {
 		case discordgo.InteractionApplicationCommand:
 			subCommand := data.Options[0]
-			var guildMap map[string]GuildData
-			ReadJson("guilds.json", &guildMap)
-			guildData := guildMap[i.GuildID]
 			switch subCommand.Name {
-			case "mod":
-				name := subCommand.Options[0].StringValue()
-				if mods[name] == nil {
-					RespondError(i, "Invalid Mod Name", fmt.Sprintf("The mod `%s` does not exist. Please use the autocomplete list for a valid mod.", name))
-					return
-				}
-				guildData.TrackedMods[name] = true
-				guildData.TrackAll = false
-				RespondSuccess(i, fmt.Sprintf("Added `%s` to tracked mods", name))
-			case "author":
-				name := subCommand.Options[0].StringValue()
-				author := authors[name]
-				if author == nil {
-					RespondError(i, "Invalid Author Name", fmt.Sprintf("The author `%s` does not exist. Please use the autocomplete list for a valid author.", name))
-					return
-				}
-				guildData.TrackedAuthors[name] = true
-				guildData.TrackAll = false
-				for _, mod := range author.Mods {
-					guildData.TrackedMods[mod.Name] = true
-				}
-				RespondSuccess(i, fmt.Sprintf("Added `%s` to tracked authors.", name))
-			case "file":
-				id := subCommand.Options[0].Value.(string)
-				url := data.Resolved.Attachments[id].URL
-				resp, err := http.Get(url)
-				if err != nil {
-					RespondError(i, "Invalid Attachment", fmt.Sprintf("Could not get response from %s", url))
+			case "version":
+				value := subCommand.Options[0].StringValue()
+				if value == "default" {
+					value = ""
+				} else if !slices.Contains(FactorioVersions(), value) {
+					RespondError(m, i, "Invalid Version", fmt.Sprintf("No mods exist for Factorio version `%s`. Please use the autocomplete list for a valid version.", value))
 					return
 				}
-				defer resp.Body.Close()
-				body, err := io.ReadAll(resp.Body)
-				if err != nil {
-					RespondError(i, "Invalid Attachment", "Failed to read response body")
-					return
+				ok := UpdateGuild(m, i, func(guildData *GuildData) {
+					guildData.Version = value
+				})
+				if ok {
+					RespondSuccess(m, i, fmt.Sprintf("Default Factorio version set to `%s`", Ternary(value == "", config.DefaultVersion+" (default)", value)))
 				}
-				var list ModList
-				if err := json.Unmarshal(body, &list); err != nil {
-					RespondError(i, "Invalid Attachment", "Failed to parse file")
-					return
+			case "delivery":
+				options := MapOptions(subCommand.Options)
+				mode := options["mode"].StringValue()
+				schedule := DigestSchedule{Interval: "hourly", LastSent: time.Now().UTC()}
+				if options["schedule"] != nil {
+					schedule.Interval = options["schedule"].StringValue()
 				}
-
-				vanillaMods := map[string]bool{"base": true, "space-age": true, "quality": true, "elevated-rail": true}
-				for _, mod := range list.Mods {
-					if mod.Enabled && !vanillaMods[mod.Name] {
-						guildData.TrackedMods[mod.Name] = true
+				if options["hour"] != nil {
+					schedule.Hour = int(options["hour"].IntValue())
+					if schedule.Hour < 0 || schedule.Hour > 23 {
+						RespondError(m, i, "Invalid Hour", "The digest hour must be between 0 and 23.")
+						return
 					}
 				}
-
-				RespondSuccess(i, "Added enabled mods to the tracked list")
-			case "all":
-				value := subCommand.Options[0].BoolValue()
-				guildData.TrackAll = value
-				RespondSuccess(i, fmt.Sprintf("%s tracking of all mods", Ternary(value, "Enabled", "Disabled")))
-			case "changelogs":
-				value := subCommand.Options[0].BoolValue()
-				guildData.Changelogs = value
-				RespondSuccess(i, fmt.Sprintf("%s changelog updates", Ternary(value, "Enabled", "Disabled")))
-			case "enabled":
-				value := subCommand.Options[0].BoolValue()
-				if value && guildData.Channel == "" {
-					RespondError(i, "Unset Update Channel", "Please set an update channel with `/track set_channel` before enabling mod updates.")
-					return
-				}
-				guildData.TrackEnabled = value
-				RespondSuccess(i, fmt.Sprintf("%s mod update messages", Ternary(value, "Enabled", "Disabled")))
-			case "set_channel":
-				channel := subCommand.Options[0].ChannelValue(s)
-				if channel.Type != 0 && channel.Type != 5 {
-					RespondError(i, "Invalid Channel Type", fmt.Sprintf("<#%s> is not a text channel.", channel.ID))
-					return
-				}
-				permissions, err := s.State.UserChannelPermissions(s.State.User.ID, channel.ID)
-				if err != nil {
-					RespondDefaultError(i)
-				}
-				if permissions&0x400 == 0 {
-					RespondError(i, "Invalid Permissions", fmt.Sprintf("Cannot view channel <#%s>", channel.ID))
-					return
-				}
-				if permissions&0x800 == 0 {
-					RespondError(i, "Invalid Permissions", fmt.Sprintf("Cannot send messages in <#%s>", channel.ID))
-					return
-				}
-				if permissions&0x4000 == 0 {
-					RespondError(i, "Invalid Permissions", fmt.Sprintf("Cannot embed links in <#%s>", channel.ID))
-					return
+				if options["weekday"] != nil {
+					for day := time.Sunday; day <= time.Saturday; day++ {
+						if day.String() == options["weekday"].StringValue() {
+							schedule.Weekday = int(day)
+						}
+					}
 				}
-
-				guildData.Channel = channel.ID
-				RespondSuccess(i, fmt.Sprintf("Update channel set to <#%s>", channel.ID))
-			case "list":
-				if len(guildData.TrackedMods) == 0 && len(guildData.TrackedAuthors) == 0 {
-					RespondSuccess(i, "No tracked mods or authors")
+				ok := UpdateGuild(m, i, func(guildData *GuildData) {
+					guildData.Delivery = mode
+					if mode == DeliveryDigest {
+						guildData.Digest = schedule
+					}
+				})
+				if !ok {
 					return
 				}
-
-				var modArr []string
-				for mod := range guildData.TrackedMods {
-					modArr = append(modArr, mod)
-				}
-				modOut := Truncate(fmt.Sprintf("**Mods:**\n%s\n\n", strings.Join(modArr, ", ")), 2000)
-
-				var authorArr []string
-				for author := range guildData.TrackedAuthors {
-					authorArr = append(authorArr, author)
-				}
-				authorOut := Truncate(fmt.Sprintf("**Authors:**\n%s", strings.Join(authorArr, ", ")), 2000)
-
-				RespondSuccess(i, modOut + authorOut)
-			case "test":
-				_, err := s.ChannelMessageSendEmbed(guildData.Channel, &discordgo.MessageEmbed{
-					Description: "Mod Update Test",
-					Color:       colors.Blue,
-				})
-				if err != nil {
-					RespondError(i, "Failed to send test mod update", "```"+err.Error()+"```")
-				} else {
-					RespondSuccess(i, "Mod update test successful")
+				switch mode {
+				case DeliveryDigest:
+					RespondSuccess(m, i, fmt.Sprintf("Updates will be sent as a digest %s", schedule))
+				case DeliveryBatch:
+					RespondSuccess(m, i, "Updates will be batched into one message per check")
+				default:
+					RespondSuccess(m, i, "Updates will be sent immediately")
 				}
 			}
-			guildMap[i.GuildID] = guildData
-			WriteJson("guilds.json", guildMap)
 		case discordgo.InteractionApplicationCommandAutocomplete:
-            var choices []*discordgo.ApplicationCommandOptionChoice
+			var choices []*discordgo.ApplicationCommandOptionChoice
 			focused := FocusedOption(data.Options[0].Options)
 			switch focused.Name {
-			case "mod":
-				modArr := ModAutocomplete(versions["all"], focused.StringValue())
-				modArr = VersionSort(modArr)
-                choices = ModChoices(modArr)
-            case "author":
-                authorArr := AuthorAutocomplete(focused.StringValue())
-                choices = AuthorChoices(authorArr)
+			case "version":
+				choices = StringChoices(append(FactorioVersions(), "default"))
 			}
-            RespondChoices(i, choices)
+			RespondChoices(m, i, choices)
 		}
 	}
 
-	untrack := NewCommand("untrack", "Removes mods from the list of tracked mods").SetPermission(discordgo.PermissionManageServer)
-	commands = append(commands, untrack)
-	untrack.AddOption("mod", "Removes a mod from the list of tracked mods").AddOption("mod", "Mod name").SetAutocomplete()
-	untrack.AddOption("author", "Removes an author from the list of tracked authors").AddOption("author", "Author name").SetAutocomplete()
-	untrack.AddOption("all", "Removes all mods and authors from both tracked lists").SetType("command")
-	untrack.Handler = func(i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
-		switch i.Type {
-		case discordgo.InteractionApplicationCommand:
-			subCommand := data.Options[0]
-			var guildMap map[string]GuildData
-			ReadJson("guilds.json", &guildMap)
-			guildData := guildMap[i.GuildID]
-			switch subCommand.Name {
-			case "mod":
-				name := subCommand.Options[0].StringValue()
-				if mods[name] == nil {
-					RespondError(i, "Invalid Mod Name", fmt.Sprintf("The mod `%s` does not exist. Please use the autocomplete list for a valid mod.", name))
-                    return
-				}
-
-				delete(guildData.TrackedMods, name)
-				RespondSuccess(i, fmt.Sprintf("Removed `%s` from tracked mods", name))
-            case "author":
-                name := subCommand.Options[0].StringValue()
-                author := authors[name]
-                if author == nil {
-                    RespondError(i, "Invalid Author Name", fmt.Sprintf("The author `%s` does not exist. Please use the autocomplete for a valid name.", name))
-                    return
-                }
-
-                for _, mod := range author.Mods {
-                    delete(guildData.TrackedMods, mod.Name)
-                }
-                delete(guildData.TrackedAuthors, name)
-                RespondSuccess(i, fmt.Sprintf("Removed `%s` from tracked authors", name))
-            case "all":
-                guildData.TrackedMods = map[string]bool{}
-                guildData.TrackedAuthors = map[string]bool{}
-                RespondSuccess(i, "Removed all mods and authors from the tracked lists")
-			}
-            guildMap[i.GuildID] = guildData
-            WriteJson("guilds.json", guildMap)
-        case discordgo.InteractionApplicationCommandAutocomplete:
-            var choices []*discordgo.ApplicationCommandOptionChoice
-            focused := FocusedOption(data.Options[0].Options)
-            var guildMap map[string]GuildData
-            ReadJson("guilds.json", &guildMap)
-            guildData := guildMap[i.GuildID]
-            switch focused.Name {
-            case "mod":
-                var modArr []*Mod
-                for name := range guildData.TrackedMods {
-                    modArr = append(modArr, mods[name])
-                }
-                choices = ModChoices(ModAutocomplete(modArr, focused.StringValue()))
-            case "author":
-				authorArr := AuthorAutocompleteList(guildData.TrackedAuthors, focused.StringValue())
-                choices = AuthorChoices(authorArr)
-            }
-            RespondChoices(i, choices)
-		}
-	}
+	router := NewRouter()
+	router.HandleComponent(pagePrefix, DeferComponent(HandlePageComponent))
 
 	var retCommands []*discordgo.ApplicationCommand
-	retHandlers := map[string]func(i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData){}
 	for _, command := range commands {
 		retCommands = append(retCommands, command.Compute())
-		retHandlers[command.Name] = command.Handler
+		command.Register(router)
 	}
 
-	return retCommands, retHandlers
+	return retCommands, router
+}
+
+// ModVersionChoices autocompletes a mod option and version options listing
+// that mod's most recent releases.
+func ModVersionChoices(options []*discordgo.ApplicationCommandInteractionDataOption, optionMap map[string]*discordgo.ApplicationCommandInteractionDataOption) []*discordgo.ApplicationCommandOptionChoice {
+	focused := FocusedOption(options)
+	switch focused.Name {
+	case "mod":
+		modArr := ModAutocomplete(versions["all"], focused.StringValue())
+		modArr = VersionSort(modArr)
+		return ModChoices(modArr)
+	case "version", "from", "to":
+		name := optionMap["mod"]
+		if name == nil {
+			return nil
+		}
+
+		mod := mods[name.StringValue()]
+		if mod == nil {
+			return nil
+		}
+
+		fullMod, err := mod.Request(true)
+		if err != nil {
+			return nil
+		}
+
+		var versionArr []string
+		for i := 1; i <= len(fullMod.Releases) && i <= 25; i++ {
+			versionArr = append(versionArr, fullMod.Releases[len(fullMod.Releases)-i].Version)
+		}
+		return StringChoices(versionArr)
+	}
+	return nil
 }
 
 func Choice(name, value string) *discordgo.ApplicationCommandOptionChoice {
@@ -485,8 +1190,8 @@ func StringChoices(sArr []string) (choices []*discordgo.ApplicationCommandOption
 	return choices
 }
 
-func RespondChoices(i *discordgo.InteractionCreate, choices []*discordgo.ApplicationCommandOptionChoice) {
-	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+func RespondChoices(m Messenger, i *discordgo.InteractionCreate, choices []*discordgo.ApplicationCommandOptionChoice) {
+	m.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
 		Data: &discordgo.InteractionResponseData{
 			Choices: choices,
@@ -494,27 +1199,457 @@ func RespondChoices(i *discordgo.InteractionCreate, choices []*discordgo.Applica
 	})
 }
 
-func RespondDefaultError(i *discordgo.InteractionCreate) {
-	RespondError(i, "Process Failed", "There was a problem processing your request, please try again.")
+// UpdateGuild applies fn to the interaction's guild in a single store
+// transaction, responding with an error if the store could not be updated.
+func UpdateGuild(m Messenger, i *discordgo.InteractionCreate, fn func(guildData *GuildData)) bool {
+	err := guildStore.Update(i.GuildID, func(guildData *GuildData) error {
+		fn(guildData)
+		return nil
+	})
+	if err != nil {
+		slog.Error("Could not update guild", "guild", i.GuildID, "err", err)
+		RespondDefaultError(m, i)
+		return false
+	}
+	return true
+}
+
+// UpdateRoute applies fn to a route of the interaction's guild in a single
+// store transaction, responding with an error if the route does not exist.
+func UpdateRoute(m Messenger, i *discordgo.InteractionCreate, name string, fn func(route *Route)) bool {
+	found := false
+	ok := UpdateGuild(m, i, func(guildData *GuildData) {
+		route := guildData.GetRoute(name)
+		if route == nil {
+			return
+		}
+		found = true
+		fn(route)
+	})
+	if ok && !found {
+		RespondRouteError(m, i, name)
+	}
+	return ok && found
+}
+
+// AddRouteOption adds an optional route option to a subcommand, or to every
+// subcommand of a group.
+func AddRouteOption(option *CommandOptionData) {
+	isGroup := false
+	for _, child := range option.Options {
+		if len(child.Options) > 0 {
+			AddRouteOption(child)
+			isGroup = true
+		}
+	}
+	if isGroup {
+		return
+	}
+	option.SetType("command")
+	option.AddOption("route", "Update route, defaults to the main update channel").SetOptional().SetAutocomplete()
+}
+
+// RouteName returns the value of a subcommand's route option, or "" for the
+// guild's main route.
+func RouteName(subCommand *discordgo.ApplicationCommandInteractionDataOption) string {
+	option := MapOptions(subCommand.Options)["route"]
+	if option == nil {
+		return ""
+	}
+	return strings.TrimSpace(option.StringValue())
+}
+
+func RouteSuffix(name string) string {
+	if name == "" {
+		return ""
+	}
+	return fmt.Sprintf(" for route `%s`", name)
+}
+
+func RouteChoices(guildID, value string) []*discordgo.ApplicationCommandOptionChoice {
+	guildData, err := guildStore.Get(guildID)
+	if err != nil {
+		return nil
+	}
+	var routeArr []string
+	for _, name := range SortedKeys(guildData.Routes) {
+		if strings.Contains(name, value) {
+			routeArr = append(routeArr, name)
+		}
+	}
+	if len(routeArr) > 25 {
+		routeArr = routeArr[:25]
+	}
+	return StringChoices(routeArr)
+}
+
+// PingTarget validates the mod and author options of a ping subcommand,
+// responding with an error if neither is a valid name.
+func PingTarget(m Messenger, i *discordgo.InteractionCreate, options map[string]*discordgo.ApplicationCommandInteractionDataOption) (modName, authorName string, ok bool) {
+	if options["mod"] == nil && options["author"] == nil {
+		RespondError(m, i, "Missing Target", "Please choose a mod or an author.")
+		return "", "", false
+	}
+	if options["mod"] != nil {
+		modName = options["mod"].StringValue()
+		if mods[modName] == nil {
+			RespondError(m, i, "Invalid Mod Name", fmt.Sprintf("The mod `%s` does not exist. Please use the autocomplete list for a valid mod.", modName))
+			return "", "", false
+		}
+	}
+	if options["author"] != nil {
+		authorName = options["author"].StringValue()
+		if authors[authorName] == nil {
+			RespondError(m, i, "Invalid Author Name", fmt.Sprintf("The author `%s` does not exist. Please use the autocomplete list for a valid author.", authorName))
+			return "", "", false
+		}
+	}
+	return modName, authorName, true
+}
+
+func PingTargetName(modName, authorName string) string {
+	if modName != "" && authorName != "" {
+		return fmt.Sprintf("`%s` and mods by `%s`", modName, authorName)
+	}
+	if modName != "" {
+		return fmt.Sprintf("`%s`", modName)
+	}
+	return fmt.Sprintf("mods by `%s`", authorName)
+}
+
+// UpdateUser applies fn to the interaction user's subscriptions in a single
+// store transaction, responding with an error if the store could not be updated.
+func UpdateUser(m Messenger, i *discordgo.InteractionCreate, fn func(userData *UserData)) bool {
+	userID := InteractionUserID(i)
+	err := userStore.Update(userID, func(userData *UserData) error {
+		fn(userData)
+		return nil
+	})
+	if err != nil {
+		slog.Error("Could not update user", "user", userID, "err", err)
+		RespondDefaultError(m, i)
+		return false
+	}
+	return true
+}
+
+// AttachmentModList downloads and parses an attached mod-list.json, save file
+// or zipped mods folder, responding with an error if it fails.
+func AttachmentModList(m Messenger, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData, option *discordgo.ApplicationCommandInteractionDataOption) (ModList, bool) {
+	var list ModList
+	attachment := data.Resolved.Attachments[option.Value.(string)]
+	tooLarge := fmt.Sprintf("Files can be at most %d MiB.", maxImportSize>>20)
+	if int64(attachment.Size) > maxImportSize {
+		RespondError(m, i, "Attachment Too Large", tooLarge)
+		return list, false
+	}
+	resp, err := http.Get(attachment.URL)
+	if err != nil {
+		RespondError(m, i, "Invalid Attachment", fmt.Sprintf("Could not get response from %s", attachment.URL))
+		return list, false
+	}
+	defer resp.Body.Close()
+	body, err := ReadLimited(resp.Body, maxImportSize)
+	if errors.Is(err, ErrTooLarge) {
+		RespondError(m, i, "Attachment Too Large", tooLarge)
+		return list, false
+	}
+	if err != nil {
+		RespondError(m, i, "Invalid Attachment", "Failed to read response body")
+		return list, false
+	}
+	list, err = ImportModList(body)
+	if err != nil {
+		RespondError(m, i, "Invalid Attachment", fmt.Sprintf("Failed to parse file: %v", err))
+		return list, false
+	}
+	return list, true
+}
+
+// AuthorPages lists every mod of an author, most recently released first.
+func AuthorPages(author *Author, thumbnail string) []*discordgo.MessageEmbed {
+	modArr := slices.Clone(author.Mods)
+	slices.SortFunc(modArr, func(a, b Mod) int {
+		return Ternary(a.LatestRelease.ReleasedAt > b.LatestRelease.ReleasedAt, -1, 1)
+	})
+
+	lines := []string{"**Recent releases:**"}
+	for _, mod := range modArr {
+		latest := mod.LatestRelease
+		lines = append(lines, fmt.Sprintf("- [%s](%s) - %s - %s", mod.Title, mod.URL(), latest.Version, Timestamp(latest.ReleasedAt)))
+	}
+
+	return PageEmbeds(discordgo.MessageEmbed{
+		Title:     author.Name,
+		URL:       author.URL(),
+		Color:     colors.Gold,
+		Thumbnail: &discordgo.MessageEmbedThumbnail{URL: thumbnail},
+		Fields: []*discordgo.MessageEmbedField{{
+			Value:  fmt.Sprintf("**Total Mods:** %d", len(author.Mods)),
+			Inline: true,
+		}, {
+			Value:  fmt.Sprintf("**Total Downloads:** %d", author.Downloads),
+			Inline: true,
+		}},
+	}, lines, 2000)
+}
+
+// ChangelogPages renders the changelog of release, or of every version after
+// from up to release if isRange is set.
+func ChangelogPages(fullMod FullMod, release Release, from string, isRange bool) []*discordgo.MessageEmbed {
+	version := release.Version
+	title := fmt.Sprintf("%s %s", Truncate(fullMod.Title, 256-len(version)), version)
+	var description string
+	if isRange {
+		merged, count := MergeChangelog(fullMod.ChangelogEntries(), from, version)
+		description = FormatChangelogCategories(merged, fullMod.IssueURL())
+		if description == "" {
+			description = "No changelog for these versions"
+		}
+		title = fmt.Sprintf("%s %s to %s (%d %s)", Truncate(fullMod.Title, 200), Ternary(from == "", "start", from), version, count, Ternary(count == 1, "version", "versions"))
+	} else {
+		for _, entry := range fullMod.ChangelogEntries() {
+			if CompareVersions(entry.Version, version) == 0 {
+				description = FormatChangelogCategories(entry.Categories, fullMod.IssueURL())
+				break
+			}
+		}
+		if description == "" {
+			description = fmt.Sprintf("No changelog for version %s", version)
+		}
+	}
+
+	return PageEmbeds(discordgo.MessageEmbed{
+		Title:     Truncate(title, 256),
+		URL:       fullMod.URL() + "/changelog",
+		Color:     colors.Gold,
+		Thumbnail: &discordgo.MessageEmbedThumbnail{URL: fullMod.GetThumbnail()},
+		Fields: []*discordgo.MessageEmbedField{{
+			Value:  fmt.Sprintf("**Author:** [%s](%s)", fullMod.Owner, UserURL(fullMod.Owner)),
+			Inline: true,
+		}, {
+			Value:  fmt.Sprintf("**Released:** %s", Timestamp(release.ReleasedAt)),
+			Inline: true,
+		}},
+	}, strings.Split(description, "\n"), 4096)
+}
+
+// DependentsPages lists the mods that depend on mod, split into hard and
+// optional dependents.
+func DependentsPages(mod *Mod) []*discordgo.MessageEmbed {
+	var required, optional []string
+	for _, dependent := range dependencyIndex.Dependents(mod.Name) {
+		if dependent.Dependency.IsOptional() {
+			optional = append(optional, dependent.Format())
+		} else {
+			required = append(required, dependent.Format())
+		}
+	}
+
+	lines := []string{fmt.Sprintf("**Required by (%d):**", len(required))}
+	lines = append(lines, Ternary(len(required) == 0, []string{"None"}, required)...)
+	lines = append(lines, "", fmt.Sprintf("**Optional for (%d):**", len(optional)))
+	lines = append(lines, Ternary(len(optional) == 0, []string{"None"}, optional)...)
+	if indexed, total := dependencyIndex.Coverage(); indexed < total {
+		lines = append(lines, "", fmt.Sprintf("*Dependencies of %d of %d mods indexed so far*", indexed, total))
+	}
+
+	return PageEmbeds(discordgo.MessageEmbed{
+		Title: Truncate(mod.Title, 256-11) + " dependents",
+		URL:   mod.URL() + "/dependents",
+		Color: colors.Gold,
+	}, lines, 2000)
+}
+
+// DependenciesPages lists the dependencies of a release by kind.
+func DependenciesPages(fullMod FullMod, release Release) []*discordgo.MessageEmbed {
+	var required, optional, incompatible []string
+	for _, dependency := range ParseDependencies(release.InfoJson.Dependencies) {
+		switch {
+		case dependency.IsRequired():
+			required = append(required, dependency.Format())
+		case dependency.IsOptional():
+			optional = append(optional, dependency.Format())
+		default:
+			incompatible = append(incompatible, dependency.Format())
+		}
+	}
+
+	var lines []string
+	addSection := func(title string, dependencyLines []string) {
+		if len(dependencyLines) == 0 {
+			return
+		}
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, fmt.Sprintf("**%s:**", title))
+		lines = append(lines, dependencyLines...)
+	}
+	addSection("Required", required)
+	addSection("Optional", optional)
+	addSection("Incompatible", incompatible)
+	if len(lines) == 0 {
+		lines = []string{"No dependencies"}
+	}
+
+	version := release.Version
+	return PageEmbeds(discordgo.MessageEmbed{
+		Title:     fmt.Sprintf("%s %s dependencies", Truncate(fullMod.Title, 256-len(version)-14), version),
+		URL:       fullMod.URL() + "/dependencies",
+		Thumbnail: &discordgo.MessageEmbedThumbnail{URL: fullMod.GetThumbnail()},
+		Color:     colors.Gold,
+		Fields: []*discordgo.MessageEmbedField{{
+			Value:  fmt.Sprintf("**Author:** [%s](%s)", fullMod.Owner, UserURL(fullMod.Owner)),
+			Inline: true,
+		}, {
+			Value:  fmt.Sprintf("**Factorio:** %s", release.FactorioVersion()),
+			Inline: true,
+		}},
+	}, lines, 2000)
+}
+
+// ModpackPages renders a modpack report, one section per kind of problem.
+func ModpackPages(report ModpackReport, version string) []*discordgo.MessageEmbed {
+	var lines []string
+	addSection := func(title string, problems []string) {
+		if len(problems) == 0 {
+			return
+		}
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, fmt.Sprintf("**%s (%d):**", title, len(problems)))
+		lines = append(lines, problems...)
+	}
+	addSection("Missing from the portal", report.Missing)
+	addSection("Could not be checked", report.Unchecked)
+	addSection(fmt.Sprintf("Not updated for %s", version), report.Outdated)
+	addSection(fmt.Sprintf("Only available for versions newer than %s", version), report.Newer)
+	addSection("Unsatisfied dependencies", report.Unsatisfied)
+	addSection("Incompatible mods", report.Incompatible)
+	if report.OK() {
+		lines = []string{"No problems found"}
+	}
+
+	return PageEmbeds(discordgo.MessageEmbed{
+		Title: fmt.Sprintf("Modpack check for Factorio %s", version),
+		Color: Ternary(report.OK(), colors.Green, colors.Red),
+	}, lines, 2000)
+}
+
+// SubscriptionsPages lists a user's direct message subscriptions and the mods
+// they are mentioned for in the guild, if any.
+func SubscriptionsPages(userID, guildID string) ([]*discordgo.MessageEmbed, error) {
+	userData, err := userStore.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	var mentionArr []string
+	if guildID != "" {
+		guildData, err := guildStore.Get(guildID)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range SortedKeys(guildData.Subscribers) {
+			if guildData.Subscribers[name][userID] {
+				mentionArr = append(mentionArr, name)
+			}
+		}
+	}
+
+	var lines []string
+	addSection := func(title string, names []string) {
+		if len(names) == 0 {
+			return
+		}
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, fmt.Sprintf("**%s (%d):**", title, len(names)))
+		for _, name := range names {
+			lines = append(lines, "- "+name)
+		}
+	}
+	addSection("Mods", SortedKeys(userData.Mods))
+	addSection("Authors", SortedKeys(userData.Authors))
+	addSection("Mentions in this server", mentionArr)
+	if len(lines) == 0 {
+		lines = []string{"You have no subscriptions"}
+	}
+
+	return PageEmbeds(discordgo.MessageEmbed{Color: colors.Green}, lines, 2000), nil
+}
+
+// TrackListPages lists a route's settings and tracked mods and authors.
+func TrackListPages(guildData GuildData, routeName string, route *Route) []*discordgo.MessageEmbed {
+	var lines []string
+	if route.Channel != "" {
+		lines = append(lines, fmt.Sprintf("**Channel:** <#%s>%s", route.Channel, Ternary(route.Webhook != nil, " (webhook)", "")))
+	} else {
+		lines = append(lines, "**Channel:** none")
+	}
+	if routeName == "" && len(guildData.Routes) > 0 {
+		lines = append(lines, fmt.Sprintf("**Other routes:** %s", strings.Join(SortedKeys(guildData.Routes), ", ")))
+	}
+	if len(route.Versions) > 0 {
+		lines = append(lines, fmt.Sprintf("**Factorio versions:** %s", strings.Join(SortedKeys(route.Versions), ", ")))
+	} else {
+		lines = append(lines, "**Factorio versions:** all")
+	}
+	lines = append(lines, "")
+	if route.TrackAll {
+		lines = append(lines, "**Tracking all mods**")
+		if len(route.IncludeCategories) > 0 {
+			lines = append(lines, fmt.Sprintf("**Included categories:** %s", strings.Join(SortedKeys(route.IncludeCategories), ", ")))
+		}
+		if len(route.ExcludeCategories) > 0 {
+			lines = append(lines, fmt.Sprintf("**Excluded categories:** %s", strings.Join(SortedKeys(route.ExcludeCategories), ", ")))
+		}
+		lines = append(lines, "")
+	}
+
+	if len(route.TrackedMods) == 0 && len(route.TrackedAuthors) == 0 {
+		lines = append(lines, "No tracked mods or authors")
+	} else {
+		lines = append(lines, fmt.Sprintf("**Mods (%d):**", len(route.TrackedMods)))
+		for _, name := range SortedKeys(route.TrackedMods) {
+			lines = append(lines, "- "+name)
+		}
+		lines = append(lines, "", fmt.Sprintf("**Authors (%d):**", len(route.TrackedAuthors)))
+		for _, name := range SortedKeys(route.TrackedAuthors) {
+			lines = append(lines, "- "+name)
+		}
+	}
+
+	return PageEmbeds(discordgo.MessageEmbed{Color: colors.Green}, lines, 2000)
+}
+
+func RespondRouteError(m Messenger, i *discordgo.InteractionCreate, name string) {
+	RespondError(m, i, "Invalid Route", fmt.Sprintf("The route `%s` does not exist. Create it with `/track set_channel`.", name))
+}
+
+func RespondDefaultError(m Messenger, i *discordgo.InteractionCreate) {
+	RespondError(m, i, "Process Failed", "There was a problem processing your request, please try again.")
 }
 
-func RespondError(i *discordgo.InteractionCreate, title, description string) {
-	RespondEmbed(i, discordgo.MessageEmbed{
+func RespondError(m Messenger, i *discordgo.InteractionCreate, title, description string) {
+	RespondEmbed(m, i, discordgo.MessageEmbed{
 		Title:       "ERROR: " + title,
 		Description: description,
 		Color:       colors.Red,
 	})
 }
 
-func RespondSuccess(i *discordgo.InteractionCreate, description string) {
-	RespondEmbed(i, discordgo.MessageEmbed{
+func RespondSuccess(m Messenger, i *discordgo.InteractionCreate, description string) {
+	RespondEmbed(m, i, discordgo.MessageEmbed{
 		Description: description,
 		Color:       colors.Green,
 	})
 }
 
-func RespondEmbed(i *discordgo.InteractionCreate, embed discordgo.MessageEmbed) {
-	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+func RespondEmbed(m Messenger, i *discordgo.InteractionCreate, embed discordgo.MessageEmbed) {
+	err := m.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
 			Embeds: []*discordgo.MessageEmbed{
@@ -523,7 +1658,7 @@ func RespondEmbed(i *discordgo.InteractionCreate, embed discordgo.MessageEmbed)
 		},
 	})
 	if err != nil {
-		fmt.Printf("%v\n", err)
+		slog.Error("Could not respond to interaction", "err", err)
 	}
 }
 
@@ -535,21 +1670,39 @@ func MapOptions(options []*discordgo.ApplicationCommandInteractionDataOption) ma
 	return ret
 }
 
+// SubCommand returns the invoked subcommand, descending through subcommand
+// groups.
+func SubCommand(options []*discordgo.ApplicationCommandInteractionDataOption) *discordgo.ApplicationCommandInteractionDataOption {
+	option := options[0]
+	for option.Type == discordgo.ApplicationCommandOptionSubCommandGroup {
+		option = option.Options[0]
+	}
+	return option
+}
+
+// FocusedOption returns the option being autocompleted, or an empty string
+// option if none is focused.
 func FocusedOption(options []*discordgo.ApplicationCommandInteractionDataOption) *discordgo.ApplicationCommandInteractionDataOption {
 	for _, option := range options {
 		if option.Focused {
 			return option
 		}
 	}
-	return options[1]
+	return &discordgo.ApplicationCommandInteractionDataOption{Type: discordgo.ApplicationCommandOptionString, Value: ""}
 }
 
+type CommandHandler func(m Messenger, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData)
+
 type CommandData struct {
 	Name        string
 	Description string
 	Permission  *int64
 	Options     []*CommandOptionData
-	Handler     func(i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData)
+	Handler     CommandHandler
+	Deferred    bool
+	Ephemeral   bool
+	Components  map[string]ComponentHandler
+	Modals      map[string]ModalHandler
 }
 
 type CommandOptionData struct {
@@ -558,6 +1711,7 @@ type CommandOptionData struct {
 	Description  string
 	Optional     bool
 	Autocomplete bool
+	Choices      []string
 	Options      []*CommandOptionData
 }
 
@@ -565,6 +1719,8 @@ func NewCommand(name, description string) *CommandData {
 	return &CommandData{
 		Name:        name,
 		Description: description,
+		Components:  map[string]ComponentHandler{},
+		Modals:      map[string]ModalHandler{},
 	}
 }
 
@@ -592,6 +1748,52 @@ func (data *CommandData) AddOption(name, description string) *CommandOptionData
 	return option
 }
 
+// HandleComponent routes message components whose custom ID starts with
+// prefix to handler.
+func (data *CommandData) HandleComponent(prefix string, handler ComponentHandler) *CommandData {
+	data.Components[prefix] = handler
+	return data
+}
+
+// HandleModal routes modal submits whose custom ID starts with prefix to
+// handler.
+func (data *CommandData) HandleModal(prefix string, handler ModalHandler) *CommandData {
+	data.Modals[prefix] = handler
+	return data
+}
+
+// Register adds the command's handlers to the router.
+func (data *CommandData) Register(router *Router) {
+	handler := Ternary(data.Deferred, DeferCommand(data.Handler), data.Handler)
+	if data.Ephemeral {
+		// Wrap the deferred handler so its acknowledgement is ephemeral too.
+		inner := handler
+		handler = func(m Messenger, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+			inner(EphemeralMessenger{m}, i, data)
+		}
+	}
+	router.HandleCommand(data.Name, handler)
+	for prefix, handler := range data.Components {
+		router.HandleComponent(prefix, handler)
+	}
+	for prefix, handler := range data.Modals {
+		router.HandleModal(prefix, handler)
+	}
+}
+
+// SetDeferred acknowledges the command automatically if its handler is slow
+// to respond, turning the response into an edit.
+func (data *CommandData) SetDeferred() *CommandData {
+	data.Deferred = true
+	return data
+}
+
+// SetEphemeral shows the command's responses only to the user who invoked it.
+func (data *CommandData) SetEphemeral() *CommandData {
+	data.Ephemeral = true
+	return data
+}
+
 func (data *CommandData) SetPermission(permission int64) *CommandData {
 	data.Permission = &permission
 	return data
@@ -604,8 +1806,13 @@ func (data *CommandOptionData) Compute() *discordgo.ApplicationCommandOption {
 	}
 	if len(data.Options) > 0 {
 		data.Type = "command"
+		for _, optionData := range data.Options {
+			if len(optionData.Options) > 0 {
+				data.Type = "group"
+			}
+		}
 	}
-	if data.Type != "command" {
+	if data.Type != "command" && data.Type != "group" {
 		if !data.Optional {
 			option.Required = true
 		}
@@ -616,12 +1823,17 @@ func (data *CommandOptionData) Compute() *discordgo.ApplicationCommandOption {
 			option.Type = discordgo.ApplicationCommandOptionAttachment
 		case "channel":
 			option.Type = discordgo.ApplicationCommandOptionChannel
+		case "int":
+			option.Type = discordgo.ApplicationCommandOptionInteger
+		case "role":
+			option.Type = discordgo.ApplicationCommandOptionRole
 		default:
 			option.Type = discordgo.ApplicationCommandOptionString
 			option.Autocomplete = data.Autocomplete
+			option.Choices = StringChoices(data.Choices)
 		}
 	} else {
-		option.Type = discordgo.ApplicationCommandOptionSubCommand
+		option.Type = Ternary(data.Type == "group", discordgo.ApplicationCommandOptionSubCommandGroup, discordgo.ApplicationCommandOptionSubCommand)
 		for _, optionData := range data.Options {
 			option.Options = append(option.Options, optionData.Compute())
 		}
@@ -649,6 +1861,11 @@ func (data *CommandOptionData) SetAutocomplete() *CommandOptionData {
 	return data
 }
 
+func (data *CommandOptionData) SetChoices(choices ...string) *CommandOptionData {
+	data.Choices = choices
+	return data
+}
+
 func (data *CommandOptionData) SetOptional() *CommandOptionData {
 	data.Optional = true
 	return data
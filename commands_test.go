@@ -0,0 +1,546 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// setupCommands loads the fake portal's mod list and returns the command
+// router.
+func setupCommands(t *testing.T) *Router {
+	t.Helper()
+	setupTest(t)
+	list, err := portal.ListMods()
+	if err != nil {
+		t.Fatal(err)
+	}
+	CacheModList(list.Results)
+	_, router := InitCommands()
+	return router
+}
+
+func commandInteraction(name string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		ID:      "interaction",
+		Type:    discordgo.InteractionApplicationCommand,
+		GuildID: "guild",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "user"}},
+		Data:    discordgo.ApplicationCommandInteractionData{Name: name, Options: options},
+	}}
+}
+
+func stringOption(name, value string) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionString, Value: value}
+}
+
+func subCommandOption(name string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionSubCommand, Options: options}
+}
+
+// respondedEmbed returns the single embed of the only interaction response.
+func respondedEmbed(t *testing.T, m *recordingMessenger) *discordgo.MessageEmbed {
+	t.Helper()
+	if len(m.Responses) != 1 {
+		t.Fatalf("got %d responses, want 1", len(m.Responses))
+	}
+	embeds := m.Responses[0].Data.Embeds
+	if len(embeds) != 1 {
+		t.Fatalf("got %d embeds, want 1", len(embeds))
+	}
+	return embeds[0]
+}
+
+func TestModCommand(t *testing.T) {
+	router := setupCommands(t)
+
+	tests := []struct {
+		name        string
+		mod         string
+		title       string
+		description string
+		color       int
+		thumbnail   string
+	}{
+		{"found", "example-mod", "Example Mod", "Adds examples.", colors.Gold, config.AssetsURL + "/assets/example-mod.thumb.png"},
+		{"default thumbnail", "example-lib", "Example Library", "Shared code for examples.", colors.Gold, ""},
+		{"missing", "no-such-mod", "ERROR: Invalid Mod Name", "The mod no-such-mod was not found.", colors.Red, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := &recordingMessenger{}
+			router.Dispatch(m, commandInteraction("mod", stringOption("mod", test.mod)))
+
+			embed := respondedEmbed(t, m)
+			if embed.Title != test.title || embed.Description != test.description || embed.Color != test.color {
+				t.Errorf("got %q %q %x, want %q %q %x", embed.Title, embed.Description, embed.Color, test.title, test.description, test.color)
+			}
+			if test.color == colors.Gold {
+				if embed.URL != config.PortalURL+"/mod/"+test.mod {
+					t.Errorf("URL = %q", embed.URL)
+				}
+				if embed.Thumbnail.URL != test.thumbnail {
+					t.Errorf("thumbnail = %q, want %q", embed.Thumbnail.URL, test.thumbnail)
+				}
+				if !strings.Contains(embed.Fields[0].Value, "[alice]") {
+					t.Errorf("author field = %q", embed.Fields[0].Value)
+				}
+			}
+		})
+	}
+}
+
+func TestChangelogCommand(t *testing.T) {
+	router := setupCommands(t)
+
+	tests := []struct {
+		name     string
+		options  []*discordgo.ApplicationCommandInteractionDataOption
+		title    string
+		contains []string
+		excludes []string
+	}{{
+		name:     "latest",
+		title:    "Example Mod 1.2.0",
+		contains: []string{"**Features:**\n- Added conveyor examples.", "**Bugfixes:**\n- Fixed a crash when loading saves. [#12](https://github.com/alice/example-mod/issues/12)"},
+		excludes: []string{"Initial release."},
+	}, {
+		name:     "version",
+		options:  []*discordgo.ApplicationCommandInteractionDataOption{stringOption("version", "1.0.0")},
+		title:    "Example Mod 1.0.0",
+		contains: []string{"**Features:**\n- Initial release."},
+	}, {
+		name:     "range",
+		options:  []*discordgo.ApplicationCommandInteractionDataOption{stringOption("from", "1.0.0")},
+		title:    "Example Mod 1.0.0 to 1.2.0 (2 versions)",
+		contains: []string{"- Added conveyor examples. (1.2.0)", "**Changes:**\n- Updated for Factorio 2.0. (1.1.0)"},
+		excludes: []string{"Initial release."},
+	}, {
+		name:     "invalid version",
+		options:  []*discordgo.ApplicationCommandInteractionDataOption{stringOption("version", "9.9.9")},
+		title:    "ERROR: Invalid Version",
+		contains: []string{"does not have a release for version `9.9.9`"},
+	}, {
+		name:     "version and to",
+		options:  []*discordgo.ApplicationCommandInteractionDataOption{stringOption("version", "1.1.0"), stringOption("to", "1.2.0")},
+		title:    "ERROR: Invalid Options",
+		contains: []string{"not both `version` and `to`"},
+	}, {
+		name:     "version and from",
+		options:  []*discordgo.ApplicationCommandInteractionDataOption{stringOption("from", "1.0.0"), stringOption("version", "1.1.0")},
+		title:    "Example Mod 1.0.0 to 1.1.0 (1 version)",
+		contains: []string{"- Updated for Factorio 2.0. (1.1.0)"},
+		excludes: []string{"Added conveyor examples."},
+	}, {
+		name:     "invalid range",
+		options:  []*discordgo.ApplicationCommandInteractionDataOption{stringOption("from", "1.2.0"), stringOption("to", "1.1.0")},
+		title:    "ERROR: Invalid Range",
+		contains: []string{"`1.2.0` must be older than `1.1.0`"},
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := &recordingMessenger{}
+			options := append([]*discordgo.ApplicationCommandInteractionDataOption{stringOption("mod", "example-mod")}, test.options...)
+			router.Dispatch(m, commandInteraction("changelog", options...))
+
+			embed := respondedEmbed(t, m)
+			if embed.Title != test.title {
+				t.Errorf("title = %q, want %q", embed.Title, test.title)
+			}
+			for _, s := range test.contains {
+				if !strings.Contains(embed.Description, s) {
+					t.Errorf("description %q does not contain %q", embed.Description, s)
+				}
+			}
+			for _, s := range test.excludes {
+				if strings.Contains(embed.Description, s) {
+					t.Errorf("description %q contains %q", embed.Description, s)
+				}
+			}
+		})
+	}
+}
+
+func TestTrackListCommand(t *testing.T) {
+	router := setupCommands(t)
+
+	tests := []struct {
+		name     string
+		setup    func(guildData *GuildData)
+		route    string
+		contains []string
+	}{{
+		name:     "empty",
+		setup:    func(guildData *GuildData) {},
+		contains: []string{"**Channel:** none", "**Factorio versions:** all", "No tracked mods or authors"},
+	}, {
+		name: "tracked",
+		setup: func(guildData *GuildData) {
+			guildData.Channel = "updates"
+			guildData.TrackedMods["example-mod"] = true
+			guildData.TrackedMods["old-mod"] = true
+			guildData.TrackedAuthors["alice"] = true
+			guildData.Versions["2.0"] = true
+		},
+		contains: []string{"**Channel:** <#updates>", "**Factorio versions:** 2.0", "**Mods (2):**\n- example-mod\n- old-mod", "**Authors (1):**\n- alice"},
+	}, {
+		name: "named route",
+		setup: func(guildData *GuildData) {
+			route := guildData.CreateRoute("space-age")
+			route.Channel = "sa-mods"
+			route.TrackAll = true
+			route.IncludeCategories["content"] = true
+		},
+		route:    "space-age",
+		contains: []string{"**Channel:** <#sa-mods>", "**Tracking all mods**", "**Included categories:** content"},
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			guildStore.Delete("guild")
+			err := guildStore.Update("guild", func(guildData *GuildData) error {
+				test.setup(guildData)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var options []*discordgo.ApplicationCommandInteractionDataOption
+			if test.route != "" {
+				options = append(options, stringOption("route", test.route))
+			}
+			m := &recordingMessenger{}
+			router.Dispatch(m, commandInteraction("track", subCommandOption("list", options...)))
+
+			embed := respondedEmbed(t, m)
+			for _, s := range test.contains {
+				if !strings.Contains(embed.Description, s) {
+					t.Errorf("description %q does not contain %q", embed.Description, s)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateEmbed(t *testing.T) {
+	setupTest(t)
+	fullMod, err := portal.GetFullMod("example-mod")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		changelogs  bool
+		version     string
+		isNew       bool
+		color       int
+		description string
+	}{
+		{"update", false, "1.2.0", false, colors.Blue, ""},
+		{"new mod", false, "1.0.0", true, colors.Green, ""},
+		{"changelog", true, "1.0.0", false, colors.Blue, "**Features:**\n- Initial release."},
+		{"no changelog entry", true, "0.1.0", false, colors.Blue, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			embed := UpdateEmbed(Route{Changelogs: test.changelogs}, fullMod, test.version, test.isNew)
+			if embed.Title != "Example Mod" || embed.Color != test.color || embed.Description != test.description {
+				t.Errorf("got %q %x %q, want Example Mod %x %q", embed.Title, embed.Color, embed.Description, test.color, test.description)
+			}
+			if !strings.Contains(embed.Fields[1].Value, test.version) {
+				t.Errorf("version field = %q, want %s", embed.Fields[1].Value, test.version)
+			}
+			if embed.Thumbnail == nil || embed.Thumbnail.URL != config.AssetsURL+"/assets/example-mod.thumb.png" {
+				t.Errorf("thumbnail = %+v", embed.Thumbnail)
+			}
+		})
+	}
+}
+
+func TestSubscribeMention(t *testing.T) {
+	router := setupCommands(t)
+
+	tests := []struct {
+		name  string
+		setup func(guildData *GuildData)
+		title string
+	}{
+		{"untracked", func(guildData *GuildData) {}, "ERROR: Mod Not Tracked"},
+		{"tracking disabled", func(guildData *GuildData) {
+			guildData.Channel = "updates"
+			guildData.TrackedMods["example-mod"] = true
+		}, "ERROR: Mod Not Tracked"},
+		{"no channel", func(guildData *GuildData) {
+			guildData.TrackEnabled = true
+			guildData.TrackedMods["example-mod"] = true
+		}, "ERROR: Mod Not Tracked"},
+		{"tracked mod", func(guildData *GuildData) {
+			guildData.TrackEnabled = true
+			guildData.Channel = "updates"
+			guildData.TrackedMods["example-mod"] = true
+		}, ""},
+		{"tracked author", func(guildData *GuildData) {
+			guildData.TrackEnabled = true
+			route := guildData.CreateRoute("alice")
+			route.Channel = "alice-mods"
+			route.TrackedAuthors["alice"] = true
+		}, ""},
+		{"excluded category", func(guildData *GuildData) {
+			guildData.TrackEnabled = true
+			guildData.Channel = "updates"
+			guildData.TrackAll = true
+			guildData.ExcludeCategories["content"] = true
+		}, "ERROR: Mod Not Tracked"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			guildStore.Delete("guild")
+			err := guildStore.Update("guild", func(guildData *GuildData) error {
+				test.setup(guildData)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			m := &recordingMessenger{}
+			router.Dispatch(m, commandInteraction("subscribe", subCommandOption("mod", stringOption("mod", "example-mod"), stringOption("notify", "mention"))))
+
+			embed := respondedEmbed(t, m)
+			if embed.Title != test.title {
+				t.Errorf("title = %q, want %q", embed.Title, test.title)
+			}
+			guildData, err := guildStore.Get("guild")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if subscribed := guildData.Subscribers["example-mod"]["user"]; subscribed != (test.title == "") {
+				t.Errorf("subscribed = %v", subscribed)
+			}
+		})
+	}
+}
+
+// clickNext presses the Next button of the last response.
+func clickNext(t *testing.T, router *Router, m *recordingMessenger) *discordgo.MessageEmbed {
+	t.Helper()
+	last := m.Responses[len(m.Responses)-1]
+	i, _ := pageInteraction(last, pageButton(t, last, "Next"))
+	router.Dispatch(m, i)
+	if len(m.Edits) > 0 {
+		t.Fatalf("page took the deferred path")
+	}
+	return m.Responses[len(m.Responses)-1].Data.Embeds[0]
+}
+
+func TestListCommandPages(t *testing.T) {
+	fake := setupTest(t)
+	list, err := portal.ListMods()
+	if err != nil {
+		t.Fatal(err)
+	}
+	CacheModList(list.Results)
+	_, router := InitCommands()
+
+	var dependencies []string
+	err = userStore.Update("user", func(userData *UserData) error {
+		for n := range 300 {
+			userData.Mods[fmt.Sprintf("mod-%03d", n)] = true
+			dependencies = append(dependencies, fmt.Sprintf("? optional-mod-%03d", n))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fullMod, err := json.Marshal(map[string]any{
+		"name": "example-lib", "title": "Example Library", "owner": "alice",
+		"releases": []map[string]any{{"version": "1.1.0", "info_json": map[string]any{"factorio_version": "2.0", "dependencies": dependencies}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.Override("/api/mods/example-lib/full", string(fullMod))
+
+	tests := []struct {
+		name        string
+		interaction *discordgo.InteractionCreate
+		first       string
+		second      string
+	}{{
+		name:        "subscriptions",
+		interaction: commandInteraction("subscriptions"),
+		first:       "**Mods (300):**\n- mod-000\n",
+		second:      "- mod-",
+	}, {
+		name:        "dependencies",
+		interaction: commandInteraction("dependencies", stringOption("mod", "example-lib")),
+		first:       "**Optional:**\n- optional-mod-000 - **missing from the portal**\n",
+		second:      "- optional-mod-0",
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := &recordingMessenger{}
+			router.Dispatch(m, test.interaction)
+
+			first := respondedEmbed(t, m)
+			if !strings.HasPrefix(first.Description, test.first) {
+				t.Errorf("first page %q does not start with %q", first.Description, test.first)
+			}
+			if first.Footer == nil || !strings.HasPrefix(first.Footer.Text, "Page 1/") {
+				t.Fatalf("first page footer = %+v", first.Footer)
+			}
+			second := clickNext(t, router, m)
+			if !strings.HasPrefix(second.Description, test.second) || !strings.HasPrefix(second.Footer.Text, "Page 2/") {
+				t.Errorf("second page %q %+v", second.Description, second.Footer)
+			}
+		})
+	}
+}
+
+func TestModpackPages(t *testing.T) {
+	setupTest(t)
+	var report ModpackReport
+	for n := range 200 {
+		report.Missing = append(report.Missing, fmt.Sprintf("- missing-mod-%03d", n))
+	}
+	report.Incompatible = []string{"- a is incompatible with b"}
+
+	pages := ModpackPages(report, "2.0")
+	if len(pages) < 2 {
+		t.Fatalf("got %d pages, want several", len(pages))
+	}
+	for n, page := range pages {
+		if len(page.Description) > 2000 || page.Color != colors.Red || page.Title != "Modpack check for Factorio 2.0" {
+			t.Errorf("page %d: %d characters, color %x, title %q", n, len(page.Description), page.Color, page.Title)
+		}
+	}
+	if !strings.HasPrefix(pages[0].Description, "**Missing from the portal (200):**\n- missing-mod-000") {
+		t.Errorf("first page = %q", pages[0].Description)
+	}
+	if last := pages[len(pages)-1].Description; !strings.HasSuffix(last, "**Incompatible mods (1):**\n- a is incompatible with b") {
+		t.Errorf("last page = %q", last)
+	}
+
+	pages = ModpackPages(ModpackReport{}, "2.0")
+	if len(pages) != 1 || pages[0].Description != "No problems found" || pages[0].Color != colors.Green {
+		t.Errorf("empty report = %+v", pages[0])
+	}
+}
+
+func TestUntrackRouteDeletesWebhook(t *testing.T) {
+	router := setupCommands(t)
+	m := &recordingMessenger{}
+	created, err := m.CreateWebhook("alice-mods", "Mod Portal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	provided, err := m.CreateWebhook("bob-mods", "Provided")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = guildStore.Update("guild", func(guildData *GuildData) error {
+		guildData.CreateRoute("alice").Webhook = &Webhook{ID: created.ID, Token: created.Token, Created: true}
+		guildData.CreateRoute("bob").Webhook = &Webhook{ID: provided.ID, Token: provided.Token}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"alice", "bob"} {
+		m.Responses = nil
+		router.Dispatch(m, commandInteraction("untrack", subCommandOption("route", stringOption("route", name))))
+		if embed := respondedEmbed(t, m); embed.Description != fmt.Sprintf("Deleted route `%s`", name) {
+			t.Errorf("got %q deleting %s", embed.Description, name)
+		}
+	}
+
+	// Only the webhook the bot created is deleted.
+	if _, ok := m.Webhooks[created.ID]; ok {
+		t.Error("created webhook not deleted with its route")
+	}
+	if _, ok := m.Webhooks[provided.ID]; !ok {
+		t.Error("user provided webhook deleted")
+	}
+}
+
+func TestSubscriptionsArePrivate(t *testing.T) {
+	router := setupCommands(t)
+	err := userStore.Update("user", func(userData *UserData) error {
+		for n := range 300 {
+			userData.Mods[fmt.Sprintf("mod-%03d", n)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	interactions := []*discordgo.InteractionCreate{
+		commandInteraction("subscribe", subCommandOption("author", stringOption("author", "alice"))),
+		commandInteraction("unsubscribe", subCommandOption("author", stringOption("author", "alice"))),
+		commandInteraction("subscribe", subCommandOption("mod", stringOption("mod", "missing-mod"))),
+		commandInteraction("subscriptions", subCommandOption("list")),
+	}
+	m := &recordingMessenger{}
+	for _, i := range interactions {
+		router.Dispatch(m, i)
+		response := m.Responses[len(m.Responses)-1]
+		if response.Data.Flags&discordgo.MessageFlagsEphemeral == 0 {
+			t.Errorf("%s responded publicly", InteractionName(i))
+		}
+	}
+
+	// Page buttons don't carry the user, so a click only shows the clicker's
+	// own subscriptions.
+	last := m.Responses[len(m.Responses)-1]
+	customID := pageButton(t, last, "Next")
+	if strings.Contains(customID, "user") {
+		t.Errorf("custom ID %q contains the user ID", customID)
+	}
+	i, _ := pageInteraction(last, customID)
+	i.Member.User.ID = "other"
+	router.Dispatch(m, i)
+	if description := m.Responses[len(m.Responses)-1].Data.Embeds[0].Description; strings.Contains(description, "mod-") {
+		t.Errorf("another user's click showed %q", description)
+	}
+}
+
+func TestAuthorPagesThumbnail(t *testing.T) {
+	fake := setupTest(t)
+	var modList []Mod
+	for n := range 100 {
+		modList = append(modList, Mod{
+			Name:          fmt.Sprintf("alice-mod-%03d", n),
+			Title:         fmt.Sprintf("Alice's Mod Number %03d", n),
+			Owner:         "alice",
+			LatestRelease: Release{Version: "1.0.0", ReleasedAt: "2024-01-01T00:00:00.000000Z"},
+		})
+	}
+	CacheModList(modList)
+	_, router := InitCommands()
+
+	m := &recordingMessenger{}
+	router.Dispatch(m, commandInteraction("author", stringOption("name", "alice")))
+	want := respondedEmbed(t, m).Thumbnail.URL
+	if want == "" {
+		t.Fatal("first page has no thumbnail")
+	}
+
+	clickNext(t, router, m)
+	last := m.Responses[len(m.Responses)-1]
+	i, _ := pageInteraction(last, pageButton(t, last, "Prev"))
+	router.Dispatch(m, i)
+	first := m.Responses[len(m.Responses)-1].Data.Embeds[0]
+	if first.Thumbnail == nil || first.Thumbnail.URL != want {
+		t.Errorf("thumbnail after paging = %+v, want %q", first.Thumbnail, want)
+	}
+	if got := fake.Requests("/user/alice"); got != 1 {
+		t.Errorf("author page requested %d times, want 1", got)
+	}
+}
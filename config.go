@@ -0,0 +1,269 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const defaultConfigPath = "config.yaml"
+
+type Config struct {
+	Token          string        `yaml:"token"`
+	TokenFile      string        `yaml:"token_file"`
+	DataDir        string        `yaml:"data_dir"`
+	GuildStore     string        `yaml:"guild_store"`
+	PollInterval   time.Duration `yaml:"poll_interval"`
+	DefaultVersion string        `yaml:"default_version"`
+	PortalURL      string        `yaml:"portal_url"`
+	AssetsURL      string        `yaml:"assets_url"`
+	LogLevel       string        `yaml:"log_level"`
+	CacheSize      int           `yaml:"cache_size"`
+	CacheTTL       time.Duration `yaml:"cache_ttl"`
+	CachePersist   bool          `yaml:"cache_persist"`
+	MetricsAddr    string        `yaml:"metrics_addr"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		TokenFile:      "token.txt",
+		DataDir:        ".",
+		GuildStore:     "json",
+		PollInterval:   time.Minute,
+		DefaultVersion: "2.1",
+		PortalURL:      "https://mods.factorio.com",
+		AssetsURL:      "https://assets-mod.factorio.com",
+		LogLevel:       "info",
+		CacheSize:      500,
+		CacheTTL:       time.Hour,
+	}
+}
+
+// LoadConfig builds the configuration from defaults, then the config file, then
+// MODPORTAL_* environment variables and finally command line flags.
+func LoadConfig(args []string) (Config, error) {
+	config := DefaultConfig()
+
+	flags := flag.NewFlagSet("modportal", flag.ContinueOnError)
+	configPath := flags.String("config", "", "path to the YAML config file (default "+defaultConfigPath+")")
+	flagToken := flags.String("token", "", "Discord bot token")
+	flagTokenFile := flags.String("token-file", "", "file containing the Discord bot token")
+	flagDataDir := flags.String("data-dir", "", "directory for guild and release data")
+	flagGuildStore := flags.String("guild-store", "", "guild storage backend: json or bolt")
+	flagPollInterval := flags.Duration("poll-interval", 0, "time between mod portal polls")
+	flagDefaultVersion := flags.String("default-version", "", "default Factorio version for autocomplete")
+	flagPortalURL := flags.String("portal-url", "", "mod portal base URL")
+	flagAssetsURL := flags.String("assets-url", "", "mod portal assets base URL")
+	flagLogLevel := flags.String("log-level", "", "log level: debug, info, warn or error")
+	flagCacheSize := flags.Int("cache-size", 0, "number of full mod details to cache, 0 disables the cache")
+	flagCacheTTL := flags.Duration("cache-ttl", 0, "how long full mod details stay cached")
+	flagCachePersist := flags.Bool("cache-persist", false, "save the full mod cache to the data directory")
+	flagMetricsAddr := flags.String("metrics-addr", "", "address to serve metrics on at /debug/vars, empty disables")
+	if err := flags.Parse(args); err != nil {
+		return config, err
+	}
+
+	path := *configPath
+	if path == "" {
+		path = os.Getenv("MODPORTAL_CONFIG")
+	}
+	if path != "" {
+		if err := config.readFile(path); err != nil {
+			return config, err
+		}
+	} else if err := config.readFile(defaultConfigPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return config, err
+	}
+
+	if err := config.readEnv(); err != nil {
+		return config, err
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "token":
+			config.Token = *flagToken
+		case "token-file":
+			config.TokenFile = *flagTokenFile
+		case "data-dir":
+			config.DataDir = *flagDataDir
+		case "guild-store":
+			config.GuildStore = *flagGuildStore
+		case "poll-interval":
+			config.PollInterval = *flagPollInterval
+		case "default-version":
+			config.DefaultVersion = *flagDefaultVersion
+		case "portal-url":
+			config.PortalURL = *flagPortalURL
+		case "assets-url":
+			config.AssetsURL = *flagAssetsURL
+		case "log-level":
+			config.LogLevel = *flagLogLevel
+		case "cache-size":
+			config.CacheSize = *flagCacheSize
+		case "cache-ttl":
+			config.CacheTTL = *flagCacheTTL
+		case "cache-persist":
+			config.CachePersist = *flagCachePersist
+		case "metrics-addr":
+			config.MetricsAddr = *flagMetricsAddr
+		}
+	})
+
+	if config.Token == "" && config.TokenFile != "" {
+		file, err := os.ReadFile(config.TokenFile)
+		if err != nil {
+			return config, fmt.Errorf("reading token file: %w", err)
+		}
+		config.Token = string(file)
+	}
+	config.Token = strings.TrimSpace(config.Token)
+	config.PortalURL = strings.TrimSuffix(config.PortalURL, "/")
+	config.AssetsURL = strings.TrimSuffix(config.AssetsURL, "/")
+
+	return config, config.Validate()
+}
+
+func (config *Config) readFile(path string) error {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(file, config); err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return nil
+}
+
+func (config *Config) readEnv() error {
+	fields := map[string]*string{
+		"MODPORTAL_TOKEN":           &config.Token,
+		"MODPORTAL_TOKEN_FILE":      &config.TokenFile,
+		"MODPORTAL_DATA_DIR":        &config.DataDir,
+		"MODPORTAL_GUILD_STORE":     &config.GuildStore,
+		"MODPORTAL_DEFAULT_VERSION": &config.DefaultVersion,
+		"MODPORTAL_PORTAL_URL":      &config.PortalURL,
+		"MODPORTAL_ASSETS_URL":      &config.AssetsURL,
+		"MODPORTAL_LOG_LEVEL":       &config.LogLevel,
+		"MODPORTAL_METRICS_ADDR":    &config.MetricsAddr,
+	}
+	for name, field := range fields {
+		if value, ok := os.LookupEnv(name); ok {
+			*field = value
+		}
+	}
+
+	if value, ok := os.LookupEnv("MODPORTAL_POLL_INTERVAL"); ok {
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("MODPORTAL_POLL_INTERVAL: %w", err)
+		}
+		config.PollInterval = interval
+	}
+	if value, ok := os.LookupEnv("MODPORTAL_CACHE_SIZE"); ok {
+		size, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("MODPORTAL_CACHE_SIZE: %w", err)
+		}
+		config.CacheSize = size
+	}
+	if value, ok := os.LookupEnv("MODPORTAL_CACHE_TTL"); ok {
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("MODPORTAL_CACHE_TTL: %w", err)
+		}
+		config.CacheTTL = ttl
+	}
+	if value, ok := os.LookupEnv("MODPORTAL_CACHE_PERSIST"); ok {
+		persist, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("MODPORTAL_CACHE_PERSIST: %w", err)
+		}
+		config.CachePersist = persist
+	}
+	return nil
+}
+
+func (config Config) Validate() error {
+	var errs []error
+	if config.Token == "" {
+		errs = append(errs, errors.New("no bot token set, use token, token_file or MODPORTAL_TOKEN"))
+	}
+	if config.DataDir == "" {
+		errs = append(errs, errors.New("data_dir must not be empty"))
+	}
+	if config.GuildStore != "json" && config.GuildStore != "bolt" {
+		errs = append(errs, fmt.Errorf("guild_store must be json or bolt, got %q", config.GuildStore))
+	}
+	if config.PollInterval < 10*time.Second {
+		errs = append(errs, fmt.Errorf("poll_interval must be at least 10s, got %s", config.PollInterval))
+	}
+	if config.DefaultVersion == "" {
+		errs = append(errs, errors.New("default_version must not be empty"))
+	}
+	for name, value := range map[string]string{"portal_url": config.PortalURL, "assets_url": config.AssetsURL} {
+		u, err := url.Parse(value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("%s must be an http(s) URL, got %q", name, value))
+		}
+	}
+	if config.CacheSize < 0 {
+		errs = append(errs, fmt.Errorf("cache_size must not be negative, got %d", config.CacheSize))
+	}
+	if config.CacheSize > 0 && config.CacheTTL <= 0 {
+		errs = append(errs, fmt.Errorf("cache_ttl must be positive, got %s", config.CacheTTL))
+	}
+	if _, err := config.SlogLevel(); err != nil {
+		errs = append(errs, err)
+	}
+	if config.MetricsAddr != "" {
+		if _, _, err := net.SplitHostPort(config.MetricsAddr); err != nil {
+			errs = append(errs, fmt.Errorf("metrics_addr must be host:port, got %q", config.MetricsAddr))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (config Config) SlogLevel() (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(config.LogLevel)); err != nil {
+		return level, fmt.Errorf("log_level must be debug, info, warn or error, got %q", config.LogLevel)
+	}
+	return level, nil
+}
+
+func (config Config) GuildStorePath() string {
+	return filepath.Join(config.DataDir, Ternary(config.GuildStore == "bolt", "guilds.db", "guilds.json"))
+}
+
+// UserStorePath is only used by the json backend, bolt keeps users in the
+// guild database.
+func (config Config) UserStorePath() string {
+	return filepath.Join(config.DataDir, "users.json")
+}
+
+func (config Config) ReleaseStatePath() string {
+	return filepath.Join(config.DataDir, "releases.json")
+}
+
+func (config Config) DependencyIndexPath() string {
+	return filepath.Join(config.DataDir, "dependencies.json")
+}
+
+// CachePath is empty unless the full mod cache is persisted.
+func (config Config) CachePath() string {
+	if !config.CachePersist {
+		return ""
+	}
+	return filepath.Join(config.DataDir, "modcache.json")
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, yaml string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	path := writeConfig(t, `
+token: yaml-token
+data_dir: yaml-data
+poll_interval: 2m
+log_level: warn
+cache_size: 10
+portal_url: https://portal.example.com/
+`)
+	t.Setenv("MODPORTAL_LOG_LEVEL", "error")
+	t.Setenv("MODPORTAL_CACHE_SIZE", "20")
+	t.Setenv("MODPORTAL_DATA_DIR", "env-data")
+
+	config, err := LoadConfig([]string{"-config", path, "-cache-size", "30"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		got, want any
+	}{
+		{"default", config.DefaultVersion, "2.1"},
+		{"yaml over default", config.PollInterval, 2 * time.Minute},
+		{"yaml over default", config.Token, "yaml-token"},
+		{"env over yaml", config.LogLevel, "error"},
+		{"env over yaml", config.DataDir, "env-data"},
+		{"flag over env", config.CacheSize, 30},
+		{"trailing slash trimmed", config.PortalURL, "https://portal.example.com"},
+	}
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, test.got, test.want)
+		}
+	}
+
+	// MODPORTAL_CONFIG selects the file when there is no flag.
+	t.Setenv("MODPORTAL_CONFIG", path)
+	config, err = LoadConfig([]string{"-log-level", "debug"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Token != "yaml-token" || config.LogLevel != "debug" {
+		t.Errorf("got token %q and log level %q, want yaml-token and debug", config.Token, config.LogLevel)
+	}
+}
+
+func TestLoadConfigTokenFile(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token.txt")
+	if err := os.WriteFile(tokenFile, []byte("  file-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	path := writeConfig(t, "token_file: "+tokenFile+"\n")
+
+	config, err := LoadConfig([]string{"-config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Token != "file-token" {
+		t.Errorf("got token %q, want file-token", config.Token)
+	}
+
+	// An explicit token wins over the token file.
+	t.Setenv("MODPORTAL_TOKEN", "env-token")
+	config, err = LoadConfig([]string{"-config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Token != "env-token" {
+		t.Errorf("got token %q, want env-token", config.Token)
+	}
+
+	if _, err := LoadConfig([]string{"-config", path, "-token", "", "-token-file", "missing.txt"}); err == nil {
+		t.Error("missing token file accepted")
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		env  map[string]string
+		args []string
+	}{
+		{"missing file", "", nil, []string{"-config", "missing.yaml"}},
+		{"bad yaml", "token: [", nil, nil},
+		{"unknown flag", "token: test", nil, []string{"-bogus"}},
+		{"bad env duration", "token: test", map[string]string{"MODPORTAL_POLL_INTERVAL": "soon"}, nil},
+		{"bad env int", "token: test", map[string]string{"MODPORTAL_CACHE_SIZE": "many"}, nil},
+		{"bad env bool", "token: test", map[string]string{"MODPORTAL_CACHE_PERSIST": "maybe"}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+			args := test.args
+			if test.name != "missing file" {
+				args = append([]string{"-config", writeConfig(t, test.yaml)}, args...)
+			}
+			if _, err := LoadConfig(args); err == nil {
+				t.Error("LoadConfig succeeded")
+			}
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	valid := DefaultConfig()
+	valid.Token = "test"
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("default config with a token is invalid: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(config *Config)
+		want   string
+	}{
+		{"token", func(config *Config) { config.Token = "" }, "no bot token set"},
+		{"data dir", func(config *Config) { config.DataDir = "" }, "data_dir must not be empty"},
+		{"guild store", func(config *Config) { config.GuildStore = "sqlite" }, "guild_store must be json or bolt"},
+		{"poll interval", func(config *Config) { config.PollInterval = time.Second }, "poll_interval must be at least 10s"},
+		{"default version", func(config *Config) { config.DefaultVersion = "" }, "default_version must not be empty"},
+		{"portal url", func(config *Config) { config.PortalURL = "mods.factorio.com" }, "portal_url must be an http(s) URL"},
+		{"assets url", func(config *Config) { config.AssetsURL = "ftp://assets" }, "assets_url must be an http(s) URL"},
+		{"cache size", func(config *Config) { config.CacheSize = -1 }, "cache_size must not be negative"},
+		{"cache ttl", func(config *Config) { config.CacheTTL = 0 }, "cache_ttl must be positive"},
+		{"log level", func(config *Config) { config.LogLevel = "verbose" }, "log_level must be"},
+		{"metrics addr", func(config *Config) { config.MetricsAddr = "9090" }, "metrics_addr must be host:port"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := valid
+			test.modify(&config)
+			err := config.Validate()
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("Validate() = %v, want an error containing %q", err, test.want)
+			}
+		})
+	}
+
+	// The TTL doesn't matter when the cache is disabled.
+	config := valid
+	config.CacheSize = 0
+	config.CacheTTL = 0
+	if err := config.Validate(); err != nil {
+		t.Errorf("disabled cache without a TTL is invalid: %v", err)
+	}
+
+	// Every problem is reported at once.
+	config = valid
+	config.Token = ""
+	config.CacheSize = -1
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "no bot token") || !strings.Contains(err.Error(), "cache_size") {
+		t.Errorf("Validate() = %v, want both errors", err)
+	}
+}
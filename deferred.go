@@ -0,0 +1,102 @@
+package main
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// deferThreshold is how long a handler may run before the interaction is
+// acknowledged, leaving a margin within Discord's three second deadline.
+var deferThreshold = 2 * time.Second
+
+// DeferredMessenger acknowledges an interaction if the handler hasn't
+// responded within the threshold, then turns the handler's response into an
+// edit of the original response.
+type DeferredMessenger struct {
+	Messenger
+	interaction *discordgo.Interaction
+	timer       *time.Timer
+	mu          sync.Mutex
+	responded   bool
+	deferred    bool
+}
+
+func NewDeferredMessenger(m Messenger, interaction *discordgo.Interaction, threshold time.Duration) *DeferredMessenger {
+	dm := &DeferredMessenger{Messenger: m, interaction: interaction}
+	dm.timer = time.AfterFunc(threshold, dm.deferResponse)
+	return dm
+}
+
+func (dm *DeferredMessenger) deferResponse() {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	if dm.responded {
+		return
+	}
+	responseType := discordgo.InteractionResponseDeferredChannelMessageWithSource
+	if dm.interaction.Type == discordgo.InteractionMessageComponent {
+		responseType = discordgo.InteractionResponseDeferredMessageUpdate
+	}
+	err := dm.Messenger.InteractionRespond(dm.interaction, &discordgo.InteractionResponse{Type: responseType})
+	if err != nil {
+		slog.Error("Could not defer interaction", "err", err)
+		return
+	}
+	dm.deferred = true
+}
+
+func (dm *DeferredMessenger) InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse) error {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	dm.timer.Stop()
+	if !dm.deferred || interaction.ID != dm.interaction.ID {
+		dm.responded = true
+		return dm.Messenger.InteractionRespond(interaction, resp)
+	}
+	edit := &discordgo.WebhookEdit{}
+	if data := resp.Data; data != nil {
+		edit.Content = &data.Content
+		edit.Embeds = &data.Embeds
+		edit.AllowedMentions = data.AllowedMentions
+		if data.Components != nil {
+			edit.Components = &data.Components
+		}
+	}
+	return dm.Messenger.EditResponse(interaction, edit)
+}
+
+// Stop prevents a deferred acknowledgement once the handler has returned.
+func (dm *DeferredMessenger) Stop() {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	dm.timer.Stop()
+	dm.responded = true
+}
+
+// DeferCommand wraps a command handler so slow commands are deferred.
+// Autocomplete can't be deferred and is passed through.
+func DeferCommand(handler CommandHandler) CommandHandler {
+	return func(m Messenger, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+		if i.Type != discordgo.InteractionApplicationCommand {
+			handler(m, i, data)
+			return
+		}
+		dm := NewDeferredMessenger(m, i.Interaction, deferThreshold)
+		defer dm.Stop()
+		defer Recover(dm, i)
+		handler(dm, i, data)
+	}
+}
+
+// DeferComponent wraps a component handler so slow updates are deferred.
+func DeferComponent(handler ComponentHandler) ComponentHandler {
+	return func(m Messenger, i *discordgo.InteractionCreate, data discordgo.MessageComponentInteractionData) {
+		dm := NewDeferredMessenger(m, i.Interaction, deferThreshold)
+		defer dm.Stop()
+		defer Recover(dm, i)
+		handler(dm, i, data)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func setDeferThreshold(t *testing.T, threshold time.Duration) {
+	t.Helper()
+	old := deferThreshold
+	deferThreshold = threshold
+	t.Cleanup(func() { deferThreshold = old })
+}
+
+func deferredHandler(delay time.Duration) CommandHandler {
+	return DeferCommand(func(m Messenger, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+		time.Sleep(delay)
+		RespondEmbed(m, i, discordgo.MessageEmbed{Title: "done"})
+	})
+}
+
+func TestDeferCommandFast(t *testing.T) {
+	setDeferThreshold(t, 10*time.Millisecond)
+	m := &recordingMessenger{}
+	i := commandInteraction("slow")
+	deferredHandler(0)(m, i, i.ApplicationCommandData())
+
+	if embed := respondedEmbed(t, m); embed.Title != "done" {
+		t.Errorf("got title %q, want done", embed.Title)
+	}
+	if len(m.Edits) != 0 {
+		t.Errorf("got %d edits, want 0", len(m.Edits))
+	}
+
+	// The timer must not fire after the handler has returned.
+	time.Sleep(50 * time.Millisecond)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.Responses) != 1 {
+		t.Errorf("got %d responses after the handler returned, want 1", len(m.Responses))
+	}
+}
+
+func TestDeferCommandSlow(t *testing.T) {
+	setDeferThreshold(t, 5*time.Millisecond)
+	m := &recordingMessenger{}
+	i := commandInteraction("slow")
+	deferredHandler(50*time.Millisecond)(m, i, i.ApplicationCommandData())
+
+	if len(m.Responses) != 1 || m.Responses[0].Type != discordgo.InteractionResponseDeferredChannelMessageWithSource {
+		t.Fatalf("got responses %+v, want a single deferral", m.Responses)
+	}
+	if len(m.Edits) != 1 || len(*m.Edits[0].Embeds) != 1 || (*m.Edits[0].Embeds)[0].Title != "done" {
+		t.Fatalf("got edits %+v, want the response as an edit", m.Edits)
+	}
+}
+
+func TestDeferComponentSlow(t *testing.T) {
+	setDeferThreshold(t, 5*time.Millisecond)
+	m := &recordingMessenger{}
+	i := &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		ID:   "interaction",
+		Type: discordgo.InteractionMessageComponent,
+		Data: discordgo.MessageComponentInteractionData{CustomID: "slow"},
+	}}
+	DeferComponent(func(m Messenger, i *discordgo.InteractionCreate, data discordgo.MessageComponentInteractionData) {
+		time.Sleep(50 * time.Millisecond)
+		RespondEmbed(m, i, discordgo.MessageEmbed{Title: "done"})
+	})(m, i, i.MessageComponentData())
+
+	if len(m.Responses) != 1 || m.Responses[0].Type != discordgo.InteractionResponseDeferredMessageUpdate {
+		t.Fatalf("got responses %+v, want a deferred update", m.Responses)
+	}
+	if len(m.Edits) != 1 {
+		t.Fatalf("got %d edits, want 1", len(m.Edits))
+	}
+}
+
+// A handler responding while the timer fires must either respond directly or
+// be deferred and edit, never both respond and defer.
+func TestDeferCommandRace(t *testing.T) {
+	setDeferThreshold(t, time.Millisecond)
+	for range 200 {
+		m := &recordingMessenger{}
+		i := commandInteraction("slow")
+		deferredHandler(time.Millisecond)(m, i, i.ApplicationCommandData())
+
+		if len(m.Responses) != 1 {
+			t.Fatalf("got %d responses, want 1", len(m.Responses))
+		}
+		deferred := m.Responses[0].Type == discordgo.InteractionResponseDeferredChannelMessageWithSource
+		if want := map[bool]int{true: 1, false: 0}[deferred]; len(m.Edits) != want {
+			t.Fatalf("deferred %v with %d edits, want %d", deferred, len(m.Edits), want)
+		}
+	}
+}
+
+func TestEphemeralDeferral(t *testing.T) {
+	setDeferThreshold(t, 5*time.Millisecond)
+	m := &recordingMessenger{}
+	i := commandInteraction("slow")
+	deferredHandler(50*time.Millisecond)(EphemeralMessenger{m}, i, i.ApplicationCommandData())
+
+	if len(m.Responses) != 1 || m.Responses[0].Data == nil || m.Responses[0].Data.Flags&discordgo.MessageFlagsEphemeral == 0 {
+		t.Fatalf("got responses %+v, want an ephemeral deferral", m.Responses)
+	}
+	if len(m.Edits) != 1 {
+		t.Errorf("got %d edits, want 1", len(m.Edits))
+	}
+}
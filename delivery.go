@@ -0,0 +1,261 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	DeliveryImmediate = "immediate"
+	DeliveryBatch     = "batch"
+	DeliveryDigest    = "digest"
+)
+
+// DigestSchedule describes when a guild in digest mode receives its summary.
+// Times are in UTC.
+type DigestSchedule struct {
+	Interval string    `json:"interval"`
+	Hour     int       `json:"hour"`
+	Weekday  int       `json:"weekday"`
+	LastSent time.Time `json:"last_sent"`
+}
+
+// Last returns the most recent scheduled digest time at or before now.
+func (schedule DigestSchedule) Last(now time.Time) time.Time {
+	now = now.UTC()
+	switch schedule.Interval {
+	case "daily":
+		last := time.Date(now.Year(), now.Month(), now.Day(), schedule.Hour, 0, 0, 0, time.UTC)
+		if last.After(now) {
+			last = last.AddDate(0, 0, -1)
+		}
+		return last
+	case "weekly":
+		last := time.Date(now.Year(), now.Month(), now.Day(), schedule.Hour, 0, 0, 0, time.UTC)
+		last = last.AddDate(0, 0, -int((7+now.Weekday()-time.Weekday(schedule.Weekday))%7))
+		if last.After(now) {
+			last = last.AddDate(0, 0, -7)
+		}
+		return last
+	default:
+		return now.Truncate(time.Hour)
+	}
+}
+
+func (schedule DigestSchedule) Due(now time.Time) bool {
+	return schedule.Last(now).After(schedule.LastSent)
+}
+
+func (schedule DigestSchedule) String() string {
+	switch schedule.Interval {
+	case "daily":
+		return fmt.Sprintf("daily at %02d:00 UTC", schedule.Hour)
+	case "weekly":
+		return fmt.Sprintf("weekly on %s at %02d:00 UTC", time.Weekday(schedule.Weekday), schedule.Hour)
+	default:
+		return "hourly"
+	}
+}
+
+// PendingRelease is a release waiting to be included in a guild's next digest.
+// It holds everything needed to render the digest so it survives restarts.
+type PendingRelease struct {
+	Route      string `json:"route"`
+	Name       string `json:"name"`
+	Title      string `json:"title"`
+	Owner      string `json:"owner"`
+	Version    string `json:"version"`
+	ReleasedAt string `json:"released_at"`
+	IsNew      bool   `json:"is_new"`
+}
+
+func NewPendingRelease(routeName string, release SpecificRelease) PendingRelease {
+	return PendingRelease{
+		Route:      routeName,
+		Name:       release.Mod.Name,
+		Title:      release.Mod.Title,
+		Owner:      release.Mod.Owner,
+		Version:    release.Release.Version,
+		ReleasedAt: release.Release.ReleasedAt,
+		IsNew:      release.IsNew,
+	}
+}
+
+// DeliverReleases sends a route's releases according to the guild's delivery
+// mode. Digest releases are queued by UpdateMods instead. Releases already in
+// mentioned are sent without mentions.
+func DeliverReleases(m Messenger, guildID, routeName string, route *Route, guildData GuildData, releases []SpecificRelease, mentioned map[string]bool) {
+	if guildData.Delivery != DeliveryBatch {
+		for _, release := range releases {
+			mentions := guildData.MentionOnce(mentioned, release.Mod.Name, release.Mod.Owner, release.Release.Version)
+			UpdateMessageSend(m, guildID, routeName, route, release.Mod, release.Release.Version, release.IsNew, mentions)
+		}
+		return
+	}
+
+	var embeds []*discordgo.MessageEmbed
+	for _, release := range releases {
+		embeds = append(embeds, UpdateEmbed(*route, release.Mod, release.Release.Version, release.IsNew))
+	}
+	i := 0
+	for _, chunk := range ChunkEmbeds(embeds) {
+		var mentions Mentions
+		for _, release := range releases[i : i+len(chunk)] {
+			mentions.Add(guildData.MentionOnce(mentioned, release.Mod.Name, release.Mod.Owner, release.Release.Version))
+		}
+		i += len(chunk)
+
+		data := &discordgo.MessageSend{Embeds: chunk}
+		mentions.Apply(data)
+		if err := SendToRoute(m, guildID, routeName, route, data, "", ""); err != nil {
+			slog.Error("Could not send releases", "guild", guildID, "route", routeName, "err", err)
+		}
+	}
+}
+
+// SendDigests sends the pending releases of every guild whose digest is due,
+// and flushes releases left over from guilds that switched away from digests.
+func SendDigests(m Messenger) {
+	guildMap, err := guildStore.All()
+	if err != nil {
+		slog.Error("Could not read guilds", "err", err)
+		return
+	}
+
+	now := time.Now().UTC()
+	for guildID, guildData := range guildMap {
+		digest := guildData.Delivery == DeliveryDigest
+		if digest && !guildData.Digest.Due(now) {
+			continue
+		}
+		if !digest && len(guildData.Pending) == 0 {
+			continue
+		}
+
+		pending := guildData.Pending
+		byRoute := map[string][]PendingRelease{}
+		for _, release := range pending {
+			byRoute[release.Route] = append(byRoute[release.Route], release)
+		}
+		mentioned := map[string]bool{}
+		for _, routeName := range SortedKeys(byRoute) {
+			releases := byRoute[routeName]
+			route := guildData.GetRoute(routeName)
+			if route == nil || route.Channel == "" {
+				continue
+			}
+			var mentions Mentions
+			for _, release := range releases {
+				mentions.Add(guildData.MentionOnce(mentioned, release.Name, release.Owner, release.Version))
+			}
+			for j, chunk := range ChunkEmbeds(DigestEmbeds(releases)) {
+				data := &discordgo.MessageSend{Embeds: chunk}
+				if j == 0 {
+					mentions.Apply(data)
+				}
+				if err := SendToRoute(m, guildID, routeName, route, data, "", ""); err != nil {
+					slog.Error("Could not send releases", "guild", guildID, "route", routeName, "err", err)
+				}
+			}
+		}
+
+		err := guildStore.Update(guildID, func(g *GuildData) error {
+			// Releases queued while the digest was being sent stay pending.
+			if len(g.Pending) >= len(pending) {
+				g.Pending = g.Pending[len(pending):]
+			}
+			if digest {
+				g.Digest.LastSent = now
+			}
+			return nil
+		})
+		if err != nil {
+			slog.Error("Could not update guild", "guild", guildID, "err", err)
+		}
+	}
+}
+
+func DigestEmbeds(releases []PendingRelease) []*discordgo.MessageEmbed {
+	var lines []string
+	for _, release := range releases {
+		mod := Mod{Name: release.Name}
+		line := fmt.Sprintf("- [%s](%s) %s by [%s](%s)", release.Title, mod.URL(), release.Version, release.Owner, UserURL(release.Owner))
+		if release.IsNew {
+			line += " **(new)**"
+		}
+		lines = append(lines, line)
+	}
+
+	var embeds []*discordgo.MessageEmbed
+	for i, description := range SplitLines(lines, 4096) {
+		embed := &discordgo.MessageEmbed{
+			Description: description,
+			Color:       colors.Blue,
+		}
+		if i == 0 {
+			embed.Title = fmt.Sprintf("Mod updates digest (%d releases)", len(releases))
+		}
+		embeds = append(embeds, embed)
+	}
+	return embeds
+}
+
+// SplitLines joins lines with newlines into chunks of at most max characters.
+func SplitLines(lines []string, max int) []string {
+	var chunks []string
+	var chunk string
+	for _, line := range lines {
+		line = Truncate(line, max)
+		if chunk != "" && len(chunk)+1+len(line) > max {
+			chunks = append(chunks, chunk)
+			chunk = ""
+		}
+		if chunk != "" {
+			chunk += "\n"
+		}
+		chunk += line
+	}
+	if chunk != "" {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// ChunkEmbeds groups embeds into messages within Discord's limits of ten embeds
+// and 6000 characters per message.
+func ChunkEmbeds(embeds []*discordgo.MessageEmbed) [][]*discordgo.MessageEmbed {
+	var chunks [][]*discordgo.MessageEmbed
+	var chunk []*discordgo.MessageEmbed
+	size := 0
+	for _, embed := range embeds {
+		length := EmbedLength(embed)
+		if len(chunk) == 10 || (len(chunk) > 0 && size+length > 6000) {
+			chunks = append(chunks, chunk)
+			chunk = nil
+			size = 0
+		}
+		chunk = append(chunk, embed)
+		size += length
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+func EmbedLength(embed *discordgo.MessageEmbed) int {
+	length := len(embed.Title) + len(embed.Description)
+	for _, field := range embed.Fields {
+		length += len(field.Name) + len(field.Value)
+	}
+	if embed.Footer != nil {
+		length += len(embed.Footer.Text)
+	}
+	if embed.Author != nil {
+		length += len(embed.Author.Name)
+	}
+	return length
+}
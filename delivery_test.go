@@ -0,0 +1,168 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestDigestScheduleLast(t *testing.T) {
+	date := func(day, hour, minute int) time.Time {
+		// January 2024 starts on a Monday.
+		return time.Date(2024, time.January, day, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name     string
+		schedule DigestSchedule
+		now      time.Time
+		want     time.Time
+	}{
+		{"hourly", DigestSchedule{}, date(2, 10, 59), date(2, 10, 0)},
+		{"daily later today", DigestSchedule{Interval: "daily", Hour: 9}, date(2, 18, 0), date(2, 9, 0)},
+		{"daily on the hour", DigestSchedule{Interval: "daily", Hour: 9}, date(2, 9, 0), date(2, 9, 0)},
+		{"daily after midnight", DigestSchedule{Interval: "daily", Hour: 23}, date(2, 0, 30), date(1, 23, 0)},
+		{"daily across months", DigestSchedule{Interval: "daily", Hour: 12}, time.Date(2024, time.March, 1, 6, 0, 0, 0, time.UTC), time.Date(2024, time.February, 29, 12, 0, 0, 0, time.UTC)},
+		{"weekly same day", DigestSchedule{Interval: "weekly", Weekday: int(time.Monday), Hour: 9}, date(8, 9, 30), date(8, 9, 0)},
+		{"weekly before the hour", DigestSchedule{Interval: "weekly", Weekday: int(time.Monday), Hour: 9}, date(8, 8, 0), date(1, 9, 0)},
+		{"weekly later in the week", DigestSchedule{Interval: "weekly", Weekday: int(time.Monday), Hour: 9}, date(7, 23, 0), date(1, 9, 0)},
+		{"weekly across midnight", DigestSchedule{Interval: "weekly", Weekday: int(time.Saturday), Hour: 23}, date(7, 0, 30), date(6, 23, 0)},
+		{"weekly across years", DigestSchedule{Interval: "weekly", Weekday: int(time.Sunday), Hour: 0}, date(1, 12, 0), time.Date(2023, time.December, 31, 0, 0, 0, 0, time.UTC)},
+		{"local time", DigestSchedule{Interval: "daily", Hour: 9}, time.Date(2024, time.January, 2, 8, 0, 0, 0, time.FixedZone("UTC+2", 2*60*60)), date(1, 9, 0)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.schedule.Last(test.now); !got.Equal(test.want) {
+				t.Errorf("Last(%s) = %s, want %s", test.now, got, test.want)
+			}
+		})
+	}
+}
+
+func TestDigestScheduleDue(t *testing.T) {
+	now := time.Date(2024, time.January, 2, 12, 0, 0, 0, time.UTC)
+	daily := DigestSchedule{Interval: "daily", Hour: 9}
+
+	tests := []struct {
+		name     string
+		lastSent time.Time
+		want     bool
+	}{
+		// A schedule that was never sent is due immediately.
+		{"never sent", time.Time{}, true},
+		// Restarting after today's digest was sent must not send it again.
+		{"sent today", time.Date(2024, time.January, 2, 9, 0, 0, 0, time.UTC), false},
+		{"sent late today", time.Date(2024, time.January, 2, 11, 0, 0, 0, time.UTC), false},
+		// A digest missed while the bot was down is sent after the restart.
+		{"missed while down", time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC), true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schedule := daily
+			schedule.LastSent = test.lastSent
+			if got := schedule.Due(now); got != test.want {
+				t.Errorf("Due = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDigestScheduleString(t *testing.T) {
+	tests := map[string]DigestSchedule{
+		"hourly":                        {},
+		"daily at 09:00 UTC":            {Interval: "daily", Hour: 9},
+		"weekly on Friday at 18:00 UTC": {Interval: "weekly", Weekday: int(time.Friday), Hour: 18},
+	}
+	for want, schedule := range tests {
+		if got := schedule.String(); got != want {
+			t.Errorf("String() = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestSplitLines(t *testing.T) {
+	if chunks := SplitLines(nil, 10); len(chunks) != 0 {
+		t.Errorf("SplitLines(nil) = %q", chunks)
+	}
+
+	chunks := SplitLines([]string{"aaaa", "bbbb", "cccc"}, 10)
+	if len(chunks) != 2 || chunks[0] != "aaaa\nbbbb" || chunks[1] != "cccc" {
+		t.Errorf("SplitLines = %q", chunks)
+	}
+
+	// A line longer than max is truncated into a chunk of its own.
+	long := strings.Repeat("x", 25)
+	chunks = SplitLines([]string{"a", long, "b"}, 10)
+	if len(chunks) != 3 || chunks[0] != "a" || chunks[1] != "xxxxxxx..." || chunks[2] != "b" {
+		t.Errorf("SplitLines with a long line = %q", chunks)
+	}
+	for _, chunk := range chunks {
+		if len(chunk) > 10 {
+			t.Errorf("chunk %q is longer than 10", chunk)
+		}
+	}
+}
+
+func TestChunkEmbeds(t *testing.T) {
+	embeds := func(n, size int) []*discordgo.MessageEmbed {
+		var embeds []*discordgo.MessageEmbed
+		for range n {
+			embeds = append(embeds, &discordgo.MessageEmbed{Description: strings.Repeat("x", size)})
+		}
+		return embeds
+	}
+	sizes := func(chunks [][]*discordgo.MessageEmbed) []int {
+		var sizes []int
+		for _, chunk := range chunks {
+			sizes = append(sizes, len(chunk))
+		}
+		return sizes
+	}
+
+	tests := []struct {
+		name   string
+		embeds []*discordgo.MessageEmbed
+		want   []int
+	}{
+		{"empty", nil, nil},
+		{"ten embeds", embeds(10, 10), []int{10}},
+		{"eleven embeds", embeds(11, 10), []int{10, 1}},
+		{"twenty one embeds", embeds(21, 10), []int{10, 10, 1}},
+		{"exactly 6000 characters", embeds(3, 2000), []int{3}},
+		{"over 6000 characters", embeds(4, 2000), []int{3, 1}},
+		{"one large embed each", embeds(2, 4096), []int{1, 1}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			chunks := ChunkEmbeds(test.embeds)
+			if got := sizes(chunks); !slices.Equal(got, test.want) {
+				t.Errorf("chunk sizes = %v, want %v", got, test.want)
+			}
+			for _, chunk := range chunks {
+				var length int
+				for _, embed := range chunk {
+					length += EmbedLength(embed)
+				}
+				if len(chunk) > 10 || length > 6000 {
+					t.Errorf("chunk of %d embeds and %d characters exceeds Discord's limits", len(chunk), length)
+				}
+			}
+		})
+	}
+}
+
+func TestEmbedLength(t *testing.T) {
+	embed := &discordgo.MessageEmbed{
+		Title:       "title",
+		Description: "description",
+		Fields:      []*discordgo.MessageEmbedField{{Name: "name", Value: "value"}},
+		Footer:      &discordgo.MessageEmbedFooter{Text: "footer"},
+		Author:      &discordgo.MessageEmbedAuthor{Name: "author"},
+		URL:         "https://example.com",
+	}
+	if got, want := EmbedLength(embed), len("title"+"description"+"name"+"value"+"footer"+"author"); got != want {
+		t.Errorf("EmbedLength = %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DependencyRequired     = "required"
+	DependencyOptional     = "optional"
+	DependencyHidden       = "hidden"
+	DependencyIncompatible = "incompatible"
+	DependencyNoLoadOrder  = "no-load-order"
+)
+
+// vanillaMods ship with the game and are never on the portal.
+var vanillaMods = map[string]bool{"base": true, "space-age": true, "quality": true, "elevated-rail": true}
+
+// Dependency is a parsed entry of an info.json dependencies list, such as
+// "? some-mod >= 1.2.0".
+type Dependency struct {
+	Kind     string
+	Name     string
+	Operator string
+	Version  string
+}
+
+// dependencyRegexp matches an optional prefix, a mod name, which may contain
+// spaces in older mods, and an optional version constraint.
+var dependencyRegexp = regexp.MustCompile(`^(?:(!|\?|\(\?\)|~)\s*)?([\w.-][\w. -]*?)(?:\s*(<=|>=|<|>|=)\s*([0-9]+(?:\.[0-9]+){0,2}))?$`)
+
+func ParseDependency(s string) (Dependency, error) {
+	match := dependencyRegexp.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return Dependency{}, fmt.Errorf("invalid dependency %q", s)
+	}
+	dependency := Dependency{
+		Kind:     DependencyRequired,
+		Name:     strings.TrimSpace(match[2]),
+		Operator: match[3],
+		Version:  match[4],
+	}
+	switch match[1] {
+	case "!":
+		dependency.Kind = DependencyIncompatible
+	case "?":
+		dependency.Kind = DependencyOptional
+	case "(?)":
+		dependency.Kind = DependencyHidden
+	case "~":
+		dependency.Kind = DependencyNoLoadOrder
+	}
+	return dependency, nil
+}
+
+func ParseDependencies(list []string) []Dependency {
+	var dependencies []Dependency
+	for _, s := range list {
+		if dependency, err := ParseDependency(s); err == nil {
+			dependencies = append(dependencies, dependency)
+		}
+	}
+	return dependencies
+}
+
+// IsRequired reports whether the dependency must be present, which includes
+// dependencies that don't affect load order.
+func (dependency Dependency) IsRequired() bool {
+	return dependency.Kind == DependencyRequired || dependency.Kind == DependencyNoLoadOrder
+}
+
+func (dependency Dependency) IsOptional() bool {
+	return dependency.Kind == DependencyOptional || dependency.Kind == DependencyHidden
+}
+
+func (dependency Dependency) Constraint() string {
+	if dependency.Operator == "" {
+		return ""
+	}
+	return dependency.Operator + " " + dependency.Version
+}
+
+// Format renders the dependency as a Discord markdown list entry, linking mods
+// found in the cache and flagging those missing from the portal.
+func (dependency Dependency) Format() string {
+	var line string
+	if mod := mods[dependency.Name]; mod != nil {
+		line = fmt.Sprintf("- [%s](%s)", mod.Title, mod.URL())
+	} else {
+		line = fmt.Sprintf("- %s", dependency.Name)
+	}
+	if constraint := dependency.Constraint(); constraint != "" {
+		line += fmt.Sprintf(" `%s`", constraint)
+	}
+	switch dependency.Kind {
+	case DependencyHidden:
+		line += " (hidden)"
+	case DependencyNoLoadOrder:
+		line += " (does not affect load order)"
+	}
+	if mods[dependency.Name] == nil && !vanillaMods[dependency.Name] {
+		line += " - **missing from the portal**"
+	}
+	return line
+}
+
+// Dependent is a mod that declares a dependency on another mod.
+type Dependent struct {
+	Mod        *Mod
+	Dependency Dependency
+}
+
+var (
+	// dependencyBackfill is how many unindexed mods each backfill fetches.
+	dependencyBackfill = 10
+	// dependencyBackfillInterval is the least time between two backfills.
+	dependencyBackfillInterval = 10 * time.Minute
+)
+
+// DependencyIndex records the dependencies of each mod's latest release. The
+// mod list only includes the Factorio version of a release, so entries come
+// from full mod details: whenever a full mod is requested, plus a few of the
+// most downloaded unindexed mods every dependencyBackfillInterval.
+type DependencyIndex struct {
+	mu           sync.Mutex
+	filename     string
+	lastBackfill time.Time
+	Mods         map[string]IndexedRelease
+}
+
+type IndexedRelease struct {
+	Version      string   `json:"version"`
+	Dependencies []string `json:"dependencies"`
+}
+
+func LoadDependencyIndex(filename string) (*DependencyIndex, error) {
+	index := &DependencyIndex{filename: filename, Mods: map[string]IndexedRelease{}}
+	err := ReadJson(filename, &index.Mods)
+	if errors.Is(err, os.ErrNotExist) {
+		return index, nil
+	}
+	return index, err
+}
+
+func (index *DependencyIndex) Save() error {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	return WriteJson(index.filename, index.Mods)
+}
+
+// Record indexes the dependencies of the newest release of a full mod.
+func (index *DependencyIndex) Record(fullMod FullMod) {
+	release := fullMod.NewestRelease()
+	if release == nil {
+		return
+	}
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	index.Mods[fullMod.Name] = IndexedRelease{Version: release.Version, Dependencies: release.InfoJson.Dependencies}
+}
+
+// Unindexed returns up to limit mods from modList whose latest release is not
+// indexed yet, most downloaded first.
+func (index *DependencyIndex) Unindexed(modList []Mod, limit int) []Mod {
+	index.mu.Lock()
+	var unindexed []Mod
+	for _, mod := range modList {
+		if mod.LatestRelease.Version == "" {
+			continue
+		}
+		if CompareVersions(index.Mods[mod.Name].Version, mod.LatestRelease.Version) < 0 {
+			unindexed = append(unindexed, mod)
+		}
+	}
+	index.mu.Unlock()
+
+	slices.SortFunc(unindexed, func(a, b Mod) int {
+		return Ternary(a.DownloadsCount >= b.DownloadsCount, -1, 1)
+	})
+	return unindexed[:min(limit, len(unindexed))]
+}
+
+// Backfill indexes up to limit unindexed mods, fetching them through the full
+// mod cache.
+func (index *DependencyIndex) Backfill(modList []Mod, limit int) {
+	for _, mod := range index.Unindexed(modList, limit) {
+		fullMod, err := mod.Request(true)
+		if err != nil {
+			slog.Warn("Could not index dependencies", "mod", mod.Name, "err", err)
+			continue
+		}
+		index.Record(fullMod)
+	}
+}
+
+// BackfillDependencies backfills the index from the cached mod list unless the
+// last backfill was less than dependencyBackfillInterval ago. It runs after
+// each poll's updates are delivered, so releases never wait on it.
+func BackfillDependencies(now time.Time) {
+	if now.Sub(dependencyIndex.lastBackfill) < dependencyBackfillInterval {
+		return
+	}
+	dependencyIndex.lastBackfill = now
+	modList := make([]Mod, 0, len(mods))
+	for _, mod := range mods {
+		modList = append(modList, *mod)
+	}
+	dependencyIndex.Backfill(modList, dependencyBackfill)
+}
+
+// Coverage returns how many of the known mods have their latest release indexed.
+func (index *DependencyIndex) Coverage() (indexed, total int) {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	for name, mod := range mods {
+		if CompareVersions(index.Mods[name].Version, mod.LatestRelease.Version) >= 0 {
+			indexed++
+		}
+	}
+	return indexed, len(mods)
+}
+
+// Dependents returns the indexed mods that depend on name, sorted by
+// downloads. Vanilla and incompatible dependencies are skipped.
+func (index *DependencyIndex) Dependents(name string) []Dependent {
+	if vanillaMods[name] {
+		return nil
+	}
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	var dependentArr []Dependent
+	for modName, release := range index.Mods {
+		mod := mods[modName]
+		if mod == nil {
+			continue
+		}
+		for _, dependency := range ParseDependencies(release.Dependencies) {
+			if dependency.Name == name && dependency.Kind != DependencyIncompatible {
+				dependentArr = append(dependentArr, Dependent{Mod: mod, Dependency: dependency})
+			}
+		}
+	}
+	slices.SortFunc(dependentArr, func(a, b Dependent) int {
+		if a.Mod.DownloadsCount != b.Mod.DownloadsCount {
+			return Ternary(a.Mod.DownloadsCount > b.Mod.DownloadsCount, -1, 1)
+		}
+		return strings.Compare(a.Mod.Name, b.Mod.Name)
+	})
+	return dependentArr
+}
+
+func (dependent Dependent) Format() string {
+	return fmt.Sprintf("- [%s](%s) - %d downloads", dependent.Mod.Title, dependent.Mod.URL(), dependent.Mod.DownloadsCount)
+}
+
+// Satisfied reports whether version meets the dependency's version constraint.
+func (dependency Dependency) Satisfied(version string) bool {
+	if dependency.Operator == "" {
+		return true
+	}
+	v, err := ParseVersion(version)
+	if err != nil {
+		return false
+	}
+	constraint, err := ParseVersion(dependency.Version)
+	if err != nil {
+		return false
+	}
+	return v.Matches(dependency.Operator, constraint)
+}
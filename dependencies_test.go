@@ -0,0 +1,219 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDependency(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Dependency
+		ok    bool
+	}{
+		{"base", Dependency{Kind: DependencyRequired, Name: "base"}, true},
+		{"some-mod >= 1.2.0", Dependency{Kind: DependencyRequired, Name: "some-mod", Operator: ">=", Version: "1.2.0"}, true},
+		{"some-mod>=1.2", Dependency{Kind: DependencyRequired, Name: "some-mod", Operator: ">=", Version: "1.2"}, true},
+		{"  some_mod  <  2  ", Dependency{Kind: DependencyRequired, Name: "some_mod", Operator: "<", Version: "2"}, true},
+		{"some-mod <= 1.0.0", Dependency{Kind: DependencyRequired, Name: "some-mod", Operator: "<=", Version: "1.0.0"}, true},
+		{"some-mod > 1.0.0", Dependency{Kind: DependencyRequired, Name: "some-mod", Operator: ">", Version: "1.0.0"}, true},
+		{"some-mod = 1.0.0", Dependency{Kind: DependencyRequired, Name: "some-mod", Operator: "=", Version: "1.0.0"}, true},
+		{"! bad-mod", Dependency{Kind: DependencyIncompatible, Name: "bad-mod"}, true},
+		{"!bad-mod", Dependency{Kind: DependencyIncompatible, Name: "bad-mod"}, true},
+		{"? optional-mod >= 0.1.0", Dependency{Kind: DependencyOptional, Name: "optional-mod", Operator: ">=", Version: "0.1.0"}, true},
+		{"?optional-mod", Dependency{Kind: DependencyOptional, Name: "optional-mod"}, true},
+		{"(?) hidden-mod", Dependency{Kind: DependencyHidden, Name: "hidden-mod"}, true},
+		{"(?)hidden-mod=1.0.0", Dependency{Kind: DependencyHidden, Name: "hidden-mod", Operator: "=", Version: "1.0.0"}, true},
+		{"~ unordered-mod", Dependency{Kind: DependencyNoLoadOrder, Name: "unordered-mod"}, true},
+		{"Old Mod With Spaces >= 0.17.1", Dependency{Kind: DependencyRequired, Name: "Old Mod With Spaces", Operator: ">=", Version: "0.17.1"}, true},
+		{"? Old Mod", Dependency{Kind: DependencyOptional, Name: "Old Mod"}, true},
+		{"", Dependency{}, false},
+		{"   ", Dependency{}, false},
+		{"!", Dependency{}, false},
+		{"(?)", Dependency{}, false},
+		{">= 1.0.0", Dependency{}, false},
+		{"some-mod >=", Dependency{}, false},
+		{"some-mod >= abc", Dependency{}, false},
+		{"some-mod => 1.0.0", Dependency{}, false},
+		{"some-mod >= 1.0.0.0", Dependency{}, false},
+		{"!? some-mod", Dependency{}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			got, err := ParseDependency(test.input)
+			if (err == nil) != test.ok {
+				t.Fatalf("ParseDependency(%q) = %+v, %v, want ok %v", test.input, got, err, test.ok)
+			}
+			if test.ok && got != test.want {
+				t.Errorf("ParseDependency(%q) = %+v, want %+v", test.input, got, test.want)
+			}
+		})
+	}
+}
+
+func TestDependencyIndexBackfill(t *testing.T) {
+	fake := setupTest(t)
+	list, err := portal.ListMods()
+	if err != nil {
+		t.Fatal(err)
+	}
+	CacheModList(list.Results)
+
+	// example-lib is indexed at its latest release, old-mod at an older one.
+	dependencyIndex.Mods["example-lib"] = IndexedRelease{Version: "1.1.0"}
+	dependencyIndex.Mods["old-mod"] = IndexedRelease{Version: "0.1.0"}
+
+	var names []string
+	for _, mod := range dependencyIndex.Unindexed(list.Results, dependencyBackfill) {
+		names = append(names, mod.Name)
+	}
+	if !slices.Equal(names, []string{"example-mod", "old-mod"}) {
+		t.Fatalf("unindexed = %v, want the most downloaded outdated mods first", names)
+	}
+
+	dependencyIndex.Backfill(list.Results, 1)
+	for name, want := range map[string]int{"example-lib": 0, "example-mod": 1, "old-mod": 0} {
+		if got := fake.Requests("/api/mods/" + name + "/full"); got != want {
+			t.Errorf("%s requested %d times, want %d", name, got, want)
+		}
+	}
+	if got := dependencyIndex.Mods["example-mod"].Version; got != "1.2.0" {
+		t.Errorf("example-mod indexed at %q, want 1.2.0", got)
+	}
+
+	dependencyIndex.Backfill(list.Results, dependencyBackfill)
+	for name, want := range map[string]int{"example-lib": 0, "example-mod": 1, "old-mod": 1} {
+		if got := fake.Requests("/api/mods/" + name + "/full"); got != want {
+			t.Errorf("%s requested %d times, want %d", name, got, want)
+		}
+	}
+	if unindexed := dependencyIndex.Unindexed(list.Results, dependencyBackfill); len(unindexed) != 0 {
+		t.Errorf("unindexed after backfill = %v", unindexed)
+	}
+}
+
+func TestDependencyIndex(t *testing.T) {
+	setupTest(t)
+	list, err := portal.ListMods()
+	if err != nil {
+		t.Fatal(err)
+	}
+	CacheModList(list.Results)
+
+	// The mod list carries no dependencies, so nothing depends on anything yet.
+	if dependentArr := dependencyIndex.Dependents("example-lib"); len(dependentArr) != 0 {
+		t.Fatalf("dependents before indexing = %v", dependentArr)
+	}
+
+	// Only the most downloaded mod is indexed when the backfill is limited.
+	dependencyIndex.Backfill(list.Results, 1)
+	if indexed, total := dependencyIndex.Coverage(); indexed != 1 || total != 3 {
+		t.Errorf("coverage = %d/%d, want 1/3", indexed, total)
+	}
+	dependencyIndex.Backfill(list.Results, dependencyBackfill)
+	if indexed, total := dependencyIndex.Coverage(); indexed != 3 || total != 3 {
+		t.Errorf("coverage = %d/%d, want 3/3", indexed, total)
+	}
+	if unindexed := dependencyIndex.Unindexed(list.Results, dependencyBackfill); len(unindexed) != 0 {
+		t.Errorf("unindexed after backfill = %v", unindexed)
+	}
+
+	tests := []struct {
+		name       string
+		dependents []string
+	}{
+		{"example-lib", []string{"example-mod"}},
+		{"missing-mod", []string{"example-mod"}},
+		// Incompatibilities and vanilla dependencies are not dependents.
+		{"old-mod", nil},
+		{"base", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var names []string
+			for _, dependent := range dependencyIndex.Dependents(test.name) {
+				names = append(names, dependent.Mod.Name)
+			}
+			if !slices.Equal(names, test.dependents) {
+				t.Errorf("dependents = %v, want %v", names, test.dependents)
+			}
+		})
+	}
+
+	// The index survives a restart.
+	if err := dependencyIndex.Save(); err != nil {
+		t.Fatal(err)
+	}
+	dependencyIndex, err = LoadDependencyIndex(config.DependencyIndexPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dependentArr := dependencyIndex.Dependents("example-lib")
+	if len(dependentArr) != 1 || !dependentArr[0].Dependency.IsRequired() {
+		t.Errorf("reloaded dependents = %+v", dependentArr)
+	}
+}
+
+func TestDependentsPages(t *testing.T) {
+	setupTest(t)
+	list, err := portal.ListMods()
+	if err != nil {
+		t.Fatal(err)
+	}
+	CacheModList(list.Results)
+
+	// Requesting a full mod indexes it.
+	if _, err := mods["example-mod"].Request(true); err != nil {
+		t.Fatal(err)
+	}
+	description := DependentsPages(mods["example-lib"])[0].Description
+	for _, s := range []string{"**Required by (1):**\n- [Example Mod]", "**Optional for (0):**\nNone", "*Dependencies of 1 of 3 mods indexed so far*"} {
+		if !strings.Contains(description, s) {
+			t.Errorf("description %q does not contain %q", description, s)
+		}
+	}
+}
+
+func TestBackfillDependencies(t *testing.T) {
+	fake := setupTest(t)
+	list, err := portal.ListMods()
+	if err != nil {
+		t.Fatal(err)
+	}
+	CacheModList(list.Results)
+	defer func(limit int) { dependencyBackfill = limit }(dependencyBackfill)
+	dependencyBackfill = 1
+
+	requests := func() int {
+		var n int
+		for _, mod := range list.Results {
+			n += fake.Requests("/api/mods/" + mod.Name + "/full")
+		}
+		return n
+	}
+
+	now := time.Now()
+	BackfillDependencies(now)
+	if got := requests(); got != 1 {
+		t.Fatalf("first backfill made %d requests, want 1", got)
+	}
+	// Backfills are throttled regardless of how often the portal is polled.
+	BackfillDependencies(now.Add(time.Minute))
+	if got := requests(); got != 1 {
+		t.Errorf("throttled backfill made %d more requests", got-1)
+	}
+	BackfillDependencies(now.Add(dependencyBackfillInterval))
+	if got := requests(); got != 2 {
+		t.Errorf("second backfill made %d requests, want 1", got-1)
+	}
+
+	// Backfilled mods are cached, so requesting them again is free.
+	if _, err := mods["example-mod"].Request(true); err != nil {
+		t.Fatal(err)
+	}
+	if got := requests(); got != 2 {
+		t.Errorf("requesting a backfilled mod made %d more requests", got-2)
+	}
+}
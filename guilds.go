@@ -1,24 +1,216 @@
 package main
 
-import "github.com/bwmarrin/discordgo"
+import (
+	"encoding/json"
+	"log/slog"
 
-type GuildData struct {
+	"github.com/bwmarrin/discordgo"
+)
+
+// Route is a channel that receives update messages along with the rules for
+// which releases are sent to it.
+type Route struct {
 	Channel        string          `json:"channel"`
 	Changelogs     bool            `json:"changelogs"`
-	TrackEnabled   bool            `json:"track_enabled"`
 	TrackAll       bool            `json:"track_all"`
 	TrackedMods    map[string]bool `json:"tracked_mods"`
 	TrackedAuthors map[string]bool `json:"tracked_authors"`
+	Versions       map[string]bool `json:"versions"`
+
+	IncludeCategories map[string]bool `json:"include_categories"`
+	ExcludeCategories map[string]bool `json:"exclude_categories"`
+
+	Webhook *Webhook `json:"webhook"`
+}
+
+// GuildData holds the settings of a guild. The embedded Route is the guild's
+// main route and is stored inline for compatibility with older data; named
+// routes live in Routes.
+type GuildData struct {
+	Route
+	TrackEnabled bool              `json:"track_enabled"`
+	Version      string            `json:"version"`
+	Routes       map[string]*Route `json:"routes"`
+
+	Delivery string           `json:"delivery"`
+	Digest   DigestSchedule   `json:"digest"`
+	Pending  []PendingRelease `json:"pending"`
+
+	ModPings    map[string][]string        `json:"mod_pings"`
+	AuthorPings map[string][]string        `json:"author_pings"`
+	Subscribers map[string]map[string]bool `json:"subscribers"`
+
+	// LegacyDMs holds direct message subscriptions decoded from data written
+	// before they moved to the user store, see MigrateLegacyDMs.
+	LegacyDMs map[string][]string `json:"-"`
+}
+
+// UnmarshalJSON also accepts subscribers stored as "mention" or "dm" per user.
+// Mentions become regular subscribers and direct messages go to LegacyDMs.
+func (guildData *GuildData) UnmarshalJSON(data []byte) error {
+	type guildDataJSON GuildData
+	aux := struct {
+		*guildDataJSON
+		Subscribers map[string]map[string]any `json:"subscribers"`
+	}{guildDataJSON: (*guildDataJSON)(guildData)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	guildData.Subscribers = map[string]map[string]bool{}
+	guildData.LegacyDMs = nil
+	for modName, users := range aux.Subscribers {
+		for user, notify := range users {
+			switch notify {
+			case true, "mention":
+				if guildData.Subscribers[modName] == nil {
+					guildData.Subscribers[modName] = map[string]bool{}
+				}
+				guildData.Subscribers[modName][user] = true
+			case "dm":
+				if guildData.LegacyDMs == nil {
+					guildData.LegacyDMs = map[string][]string{}
+				}
+				guildData.LegacyDMs[modName] = append(guildData.LegacyDMs[modName], user)
+			}
+		}
+	}
+	return nil
+}
+
+func NewGuildData() GuildData {
+	var guildData GuildData
+	guildData.init()
+	return guildData
+}
+
+func NewRoute() *Route {
+	route := &Route{}
+	route.init()
+	return route
+}
+
+// init fills in maps that may be missing from older or freshly created entries.
+func (guildData *GuildData) init() {
+	guildData.Route.init()
+	if guildData.ModPings == nil {
+		guildData.ModPings = map[string][]string{}
+	}
+	if guildData.AuthorPings == nil {
+		guildData.AuthorPings = map[string][]string{}
+	}
+	if guildData.Subscribers == nil {
+		guildData.Subscribers = map[string]map[string]bool{}
+	}
+	if guildData.Routes == nil {
+		guildData.Routes = map[string]*Route{}
+	}
+	for name, route := range guildData.Routes {
+		if route == nil {
+			route = &Route{}
+			guildData.Routes[name] = route
+		}
+		route.init()
+	}
+}
+
+func (route *Route) init() {
+	if route.TrackedMods == nil {
+		route.TrackedMods = map[string]bool{}
+	}
+	if route.TrackedAuthors == nil {
+		route.TrackedAuthors = map[string]bool{}
+	}
+	if route.Versions == nil {
+		route.Versions = map[string]bool{}
+	}
+	if route.IncludeCategories == nil {
+		route.IncludeCategories = map[string]bool{}
+	}
+	if route.ExcludeCategories == nil {
+		route.ExcludeCategories = map[string]bool{}
+	}
+}
+
+// GetRoute returns the named route, the main route for "", or nil if no such
+// route exists.
+func (guildData *GuildData) GetRoute(name string) *Route {
+	if name == "" {
+		return &guildData.Route
+	}
+	return guildData.Routes[name]
+}
+
+// CreateRoute returns the named route, creating it if it doesn't exist.
+func (guildData *GuildData) CreateRoute(name string) *Route {
+	route := guildData.GetRoute(name)
+	if route == nil {
+		route = NewRoute()
+		guildData.Routes[name] = route
+	}
+	return route
+}
+
+// AllRoutes returns the main route under "" along with every named route.
+func (guildData *GuildData) AllRoutes() map[string]*Route {
+	routes := map[string]*Route{"": &guildData.Route}
+	for name, route := range guildData.Routes {
+		routes[name] = route
+	}
+	return routes
+}
+
+func (guildData *GuildData) HasChannel() bool {
+	for _, route := range guildData.AllRoutes() {
+		if route.Channel != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsVersion reports whether updates for the given Factorio version should
+// be announced. An empty allowlist allows every version.
+func (route *Route) AllowsVersion(version string) bool {
+	return len(route.Versions) == 0 || route.Versions[version]
+}
+
+// AllowsCategories reports whether a mod passes the route's category and tag
+// filters used when tracking all mods.
+func (route *Route) AllowsCategories(mod Mod) bool {
+	labels := mod.Categories()
+	for _, label := range labels {
+		if route.ExcludeCategories[label] {
+			return false
+		}
+	}
+	if len(route.IncludeCategories) == 0 {
+		return true
+	}
+	for _, label := range labels {
+		if route.IncludeCategories[label] {
+			return true
+		}
+	}
+	return false
+}
+
+// GuildVersion returns the guild's preferred Factorio version, or the configured
+// default if the guild has none or the interaction is outside a guild.
+func GuildVersion(guildID string) string {
+	if guildID == "" {
+		return config.DefaultVersion
+	}
+	guildData, err := guildStore.Get(guildID)
+	if err != nil || guildData.Version == "" {
+		return config.DefaultVersion
+	}
+	return guildData.Version
 }
 
 func GuildCreate(s *discordgo.Session, g *discordgo.GuildCreate) {
-	var guildMap map[string]GuildData
-	ReadJson("guilds.json", &guildMap)
-	guildData, ok := guildMap[g.ID]
-	if !ok {
-		guildData.TrackedAuthors = map[string]bool{}
-		guildData.TrackedMods = map[string]bool{}
-	}
-	guildMap[g.ID] = guildData
-	WriteJson("guilds.json", guildMap)
-}
\ No newline at end of file
+	err := guildStore.Update(g.ID, func(guildData *GuildData) error { return nil })
+	if err != nil {
+		slog.Error("Could not initialize guild", "guild", g.ID, "err", err)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestGuildDataSubscribers(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		subscribers map[string]map[string]bool
+		legacyDMs   map[string][]string
+	}{{
+		name:        "current",
+		data:        `{"subscribers": {"example-mod": {"1": true}}}`,
+		subscribers: map[string]map[string]bool{"example-mod": {"1": true}},
+	}, {
+		name:        "mention",
+		data:        `{"subscribers": {"example-mod": {"1": "mention"}}}`,
+		subscribers: map[string]map[string]bool{"example-mod": {"1": true}},
+	}, {
+		name:        "dm",
+		data:        `{"subscribers": {"example-mod": {"1": "dm", "2": "mention"}, "old-mod": {"1": "dm"}}}`,
+		subscribers: map[string]map[string]bool{"example-mod": {"2": true}},
+		legacyDMs:   map[string][]string{"example-mod": {"1"}, "old-mod": {"1"}},
+	}, {
+		name:        "missing",
+		data:        `{}`,
+		subscribers: map[string]map[string]bool{},
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var guildData GuildData
+			if err := json.Unmarshal([]byte(test.data), &guildData); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(guildData.Subscribers, test.subscribers) {
+				t.Errorf("Subscribers = %v, want %v", guildData.Subscribers, test.subscribers)
+			}
+			if !reflect.DeepEqual(guildData.LegacyDMs, test.legacyDMs) {
+				t.Errorf("LegacyDMs = %v, want %v", guildData.LegacyDMs, test.legacyDMs)
+			}
+		})
+	}
+}
+
+func TestGuildDataUnmarshalRoute(t *testing.T) {
+	var guildData GuildData
+	data := `{"channel": "updates", "track_enabled": true, "tracked_mods": {"example-mod": true}, "routes": {"lib": {"channel": "libs"}}}`
+	if err := json.Unmarshal([]byte(data), &guildData); err != nil {
+		t.Fatal(err)
+	}
+	if guildData.Channel != "updates" || !guildData.TrackEnabled || !guildData.TrackedMods["example-mod"] {
+		t.Errorf("main route not decoded: %+v", guildData)
+	}
+	if guildData.Routes["lib"] == nil || guildData.Routes["lib"].Channel != "libs" {
+		t.Errorf("named route not decoded: %+v", guildData.Routes)
+	}
+}
+
+func TestMigrateLegacyDMs(t *testing.T) {
+	setupTest(t)
+	data := `{"guild": {"channel": "updates", "subscribers": {"example-mod": {"1": "dm", "2": "mention"}}}}`
+	if err := os.WriteFile(config.GuildStorePath(), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := MigrateLegacyDMs(); err != nil {
+		t.Fatal(err)
+	}
+
+	userData, err := userStore.Get("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !userData.Mods["example-mod"] {
+		t.Errorf("user 1 not subscribed: %+v", userData)
+	}
+	guildData, err := guildStore.Get("guild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if guildData.LegacyDMs != nil || guildData.Channel != "updates" {
+		t.Errorf("guild not rewritten: %+v", guildData)
+	}
+	if want := map[string]map[string]bool{"example-mod": {"2": true}}; !reflect.DeepEqual(guildData.Subscribers, want) {
+		t.Errorf("Subscribers = %v, want %v", guildData.Subscribers, want)
+	}
+}
@@ -2,33 +2,45 @@ package main
 
 import (
 	"encoding/json"
-	"log"
 	"os"
-	"time"
+	"path/filepath"
 )
 
-func ReadJson(filename string, v any) {
-	c := 0
-	for {
-		file, err := os.ReadFile(filename)
-		if err != nil {
-			panic(err)
-		}
-		if err := json.Unmarshal(file, v); err == nil {
-			return
-		} else {
-			log.Printf("Bad JSON read of %s, retrying...", filename)
-			c++
-			if c == 3 {
-				panic(err)
-			}
-			time.Sleep(time.Second)
-		}
+func ReadJson(filename string, v any) error {
+	file, err := os.ReadFile(filename)
+	if err != nil {
+		return err
 	}
+	return json.Unmarshal(file, v)
 }
 
-func WriteJson(filename string, v any) {
-    file, err := json.MarshalIndent(v, "", "    ")
-    if err != nil {panic(err)}
-    os.WriteFile(filename, file, 0644)
-}
\ No newline at end of file
+// WriteJson writes to a temporary file and renames it over the destination so
+// readers never observe a partially written file.
+func WriteJson(filename string, v any) error {
+	file, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(file); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
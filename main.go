@@ -2,6 +2,8 @@ package main
 
 import (
 	"log"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"time"
@@ -9,61 +11,105 @@ import (
 	"github.com/bwmarrin/discordgo"
 )
 
-const defaultVersion = "2.1"
-
 var (
+	config          Config
 	s               *discordgo.Session
-	commandHandlers map[string]func(*discordgo.InteractionCreate, discordgo.ApplicationCommandInteractionData)
+	messenger       Messenger
+	guildStore      GuildStore
+	userStore       UserStore
+	releaseState    *ReleaseState
+	dependencyIndex *DependencyIndex
+	portal          PortalClient
+	modCache        *FullModCache
+	router          *Router
 )
 
-func init() {
-	// testing only
-	// t := time.Now().UTC().Add(-time.Hour)
-	// os.WriteFile("time.txt", []byte(t.Format(time.RFC3339Nano)), 0644)
+func main() {
+	var err error
+	config, err = LoadConfig(os.Args[1:])
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	level, _ := config.SlogLevel()
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
 
-	file, err := os.ReadFile("token.txt")
+	if err := os.MkdirAll(config.DataDir, 0755); err != nil {
+		log.Fatalf("Could not create data directory: %v", err)
+	}
+	guildStore, userStore, err = OpenStores(config)
 	if err != nil {
-		panic(err)
+		log.Fatalf("Could not open data stores: %v", err)
+	}
+	if err := MigrateLegacyDMs(); err != nil {
+		log.Fatalf("Could not migrate direct message subscriptions: %v", err)
 	}
-	token := string(file)
-	s, err = discordgo.New("Bot " + token)
+	releaseState, err = LoadReleaseState(config.ReleaseStatePath())
 	if err != nil {
-		panic(err)
+		log.Fatalf("Could not load release state: %v", err)
+	}
+	dependencyIndex, err = LoadDependencyIndex(config.DependencyIndexPath())
+	if err != nil {
+		log.Fatalf("Could not load dependency index: %v", err)
+	}
+	portal = NewPortalClient(config.PortalURL)
+	modCache = NewFullModCache(config.CacheSize, config.CacheTTL, config.CachePath())
+	if err := modCache.Load(); err != nil {
+		slog.Warn("Could not load mod cache", "err", err)
 	}
-}
 
-func main() {
-	log.Println("Initializing Commands")
-	commands, handlers := InitCommands()
-	commandHandlers = handlers
+	s, err = discordgo.New("Bot " + config.Token)
+	if err != nil {
+		log.Fatalf("Could not create Discord session: %v", err)
+	}
+	messenger = NewSessionMessenger(s)
+
+	if config.MetricsAddr != "" {
+		go func() {
+			slog.Info("Serving metrics", "addr", config.MetricsAddr)
+			if err := http.ListenAndServe(config.MetricsAddr, MetricsHandler()); err != nil {
+				slog.Error("Could not serve metrics", "err", err)
+			}
+		}()
+	}
+
+	slog.Info("Initializing Commands")
+	commands, commandRouter := InitCommands()
+	router = commandRouter
 
-	s.AddHandler(func(s *discordgo.Session, r *discordgo.Ready) { log.Println("READY") })
+	s.AddHandler(func(s *discordgo.Session, r *discordgo.Ready) { slog.Info("READY") })
 	s.AddHandler(GuildCreate)
 	s.AddHandler(func(s *discordgo.Session, i *discordgo.InteractionCreate) {
-		data := i.ApplicationCommandData()
-		commandHandlers[data.Name](i, data)
+		router.Dispatch(messenger, i)
 	})
 
 	if err := s.Open(); err != nil {
-		panic(err)
+		log.Fatalf("Could not connect to Discord: %v", err)
 	}
 	defer s.Close()
 
-	_, err := s.ApplicationCommandBulkOverwrite(s.State.User.ID, "", commands)
+	_, err = s.ApplicationCommandBulkOverwrite(s.State.User.ID, "", commands)
 	if err != nil {
-		panic(err)
+		log.Fatalf("Could not register commands: %v", err)
 	}
 
-	log.Println("Initializing Updates")
+	slog.Info("Initializing Updates")
 	go func() {
 		for {
-			UpdateMods()
-			time.Sleep(time.Minute)
+			UpdateMods(messenger)
+			SendDigests(messenger)
+			BackfillDependencies(time.Now())
+			if err := dependencyIndex.Save(); err != nil {
+				slog.Error("Could not save dependency index", "err", err)
+			}
+			if err := modCache.Save(); err != nil {
+				slog.Error("Could not save mod cache", "err", err)
+			}
+			time.Sleep(config.PollInterval)
 		}
 	}()
 
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt)
 	<-stop
-	log.Println("Shutting down...")
+	slog.Info("Shutting down...")
 }
@@ -0,0 +1,91 @@
+package main
+
+import "github.com/bwmarrin/discordgo"
+
+// Messenger is the subset of the Discord API used by command handlers and the
+// updater, so they can be exercised without a live session.
+type Messenger interface {
+	InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse) error
+	SendMessage(channelID string, data *discordgo.MessageSend) (*discordgo.Message, error)
+	DirectMessage(userID string, data *discordgo.MessageSend) error
+	ChannelPermissions(channelID string) (int64, error)
+	SetStatus(status string) error
+	CreateWebhook(channelID, name string) (*discordgo.Webhook, error)
+	GetWebhook(webhookID, token string) (*discordgo.Webhook, error)
+	DeleteWebhook(webhookID string) error
+	ExecuteWebhook(webhookID, token string, data *discordgo.WebhookParams) (*discordgo.Message, error)
+	EditResponse(interaction *discordgo.Interaction, data *discordgo.WebhookEdit) error
+}
+
+type SessionMessenger struct {
+	Session *discordgo.Session
+}
+
+func NewSessionMessenger(s *discordgo.Session) *SessionMessenger {
+	return &SessionMessenger{Session: s}
+}
+
+func (m *SessionMessenger) InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse) error {
+	return m.Session.InteractionRespond(interaction, resp)
+}
+
+func (m *SessionMessenger) SendMessage(channelID string, data *discordgo.MessageSend) (*discordgo.Message, error) {
+	return m.Session.ChannelMessageSendComplex(channelID, data)
+}
+
+func (m *SessionMessenger) DirectMessage(userID string, data *discordgo.MessageSend) error {
+	channel, err := m.Session.UserChannelCreate(userID)
+	if err != nil {
+		return err
+	}
+	_, err = m.Session.ChannelMessageSendComplex(channel.ID, data)
+	return err
+}
+
+func (m *SessionMessenger) ChannelPermissions(channelID string) (int64, error) {
+	return m.Session.State.UserChannelPermissions(m.Session.State.User.ID, channelID)
+}
+
+func (m *SessionMessenger) SetStatus(status string) error {
+	return m.Session.UpdateCustomStatus(status)
+}
+
+func (m *SessionMessenger) CreateWebhook(channelID, name string) (*discordgo.Webhook, error) {
+	return m.Session.WebhookCreate(channelID, name, "")
+}
+
+func (m *SessionMessenger) GetWebhook(webhookID, token string) (*discordgo.Webhook, error) {
+	return m.Session.WebhookWithToken(webhookID, token)
+}
+
+func (m *SessionMessenger) DeleteWebhook(webhookID string) error {
+	return m.Session.WebhookDelete(webhookID)
+}
+
+func (m *SessionMessenger) ExecuteWebhook(webhookID, token string, data *discordgo.WebhookParams) (*discordgo.Message, error) {
+	return m.Session.WebhookExecute(webhookID, token, true, data)
+}
+
+func (m *SessionMessenger) EditResponse(interaction *discordgo.Interaction, data *discordgo.WebhookEdit) error {
+	_, err := m.Session.InteractionResponseEdit(interaction, data)
+	return err
+}
+
+// EphemeralMessenger shows message responses only to the user who invoked the
+// interaction.
+type EphemeralMessenger struct {
+	Messenger
+}
+
+func (m EphemeralMessenger) InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse) error {
+	switch resp.Type {
+	case discordgo.InteractionResponseChannelMessageWithSource, discordgo.InteractionResponseDeferredChannelMessageWithSource:
+		var data discordgo.InteractionResponseData
+		if resp.Data != nil {
+			data = *resp.Data
+		}
+		data.Flags |= discordgo.MessageFlagsEphemeral
+		resp = &discordgo.InteractionResponse{Type: resp.Type, Data: &data}
+	}
+	return m.Messenger.InteractionRespond(interaction, resp)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type sentMessage struct {
+	ChannelID string
+	Data      *discordgo.MessageSend
+}
+
+type webhookMessage struct {
+	WebhookID string
+	Data      *discordgo.WebhookParams
+}
+
+// recordingMessenger records everything sent to Discord so tests can assert
+// on the exact responses and embeds.
+type recordingMessenger struct {
+	mu              sync.Mutex
+	Responses       []*discordgo.InteractionResponse
+	Edits           []*discordgo.WebhookEdit
+	Messages        []sentMessage
+	DMs             []sentMessage
+	WebhookMessages []webhookMessage
+	Statuses        []string
+	Webhooks        map[string]*discordgo.Webhook
+
+	// Permissions is returned for every channel.
+	Permissions int64
+	// DMErr is returned by DirectMessage.
+	DMErr error
+	// DeadWebhooks records executions of webhooks that don't exist.
+	DeadWebhooks []string
+}
+
+var _ Messenger = (*recordingMessenger)(nil)
+
+func (m *recordingMessenger) InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Responses = append(m.Responses, resp)
+	return nil
+}
+
+func (m *recordingMessenger) EditResponse(interaction *discordgo.Interaction, data *discordgo.WebhookEdit) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Edits = append(m.Edits, data)
+	return nil
+}
+
+func (m *recordingMessenger) SendMessage(channelID string, data *discordgo.MessageSend) (*discordgo.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Messages = append(m.Messages, sentMessage{ChannelID: channelID, Data: data})
+	return &discordgo.Message{ChannelID: channelID, Embeds: data.Embeds}, nil
+}
+
+func (m *recordingMessenger) DirectMessage(userID string, data *discordgo.MessageSend) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.DMErr != nil {
+		return m.DMErr
+	}
+	m.DMs = append(m.DMs, sentMessage{ChannelID: userID, Data: data})
+	return nil
+}
+
+func (m *recordingMessenger) ChannelPermissions(channelID string) (int64, error) {
+	return m.Permissions, nil
+}
+
+func (m *recordingMessenger) SetStatus(status string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Statuses = append(m.Statuses, status)
+	return nil
+}
+
+func (m *recordingMessenger) CreateWebhook(channelID, name string) (*discordgo.Webhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Webhooks == nil {
+		m.Webhooks = map[string]*discordgo.Webhook{}
+	}
+	webhook := &discordgo.Webhook{ID: fmt.Sprintf("webhook%d", len(m.Webhooks)), Token: "token", ChannelID: channelID, Name: name}
+	m.Webhooks[webhook.ID] = webhook
+	return webhook, nil
+}
+
+func (m *recordingMessenger) GetWebhook(webhookID, token string) (*discordgo.Webhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	webhook, ok := m.Webhooks[webhookID]
+	if !ok || webhook.Token != token {
+		return nil, &discordgo.RESTError{Message: &discordgo.APIErrorMessage{Code: discordgo.ErrCodeUnknownWebhook}}
+	}
+	return webhook, nil
+}
+
+func (m *recordingMessenger) DeleteWebhook(webhookID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.Webhooks, webhookID)
+	return nil
+}
+
+func (m *recordingMessenger) ExecuteWebhook(webhookID, token string, data *discordgo.WebhookParams) (*discordgo.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.Webhooks[webhookID]; !ok {
+		m.DeadWebhooks = append(m.DeadWebhooks, webhookID)
+		return nil, &discordgo.RESTError{Message: &discordgo.APIErrorMessage{Code: discordgo.ErrCodeUnknownWebhook}}
+	}
+	m.WebhookMessages = append(m.WebhookMessages, webhookMessage{WebhookID: webhookID, Data: data})
+	return &discordgo.Message{Embeds: data.Embeds}, nil
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// saveHeaderLimit bounds how much of a save's level.dat is decompressed; the
+// mod list is stored near the start of the header.
+const saveHeaderLimit = 1 << 20
+
+// Size limits for imported files. They are variables so tests can lower them.
+var (
+	// maxImportSize bounds an uploaded mod list, save or mods folder.
+	maxImportSize int64 = 32 << 20
+	// maxExtractSize bounds the total size of the files read from an archive.
+	maxExtractSize int64 = 128 << 20
+	// maxJsonSize bounds each info.json and mod-list.json.
+	maxJsonSize int64 = 1 << 20
+)
+
+var ErrTooLarge = errors.New("file is too large")
+
+// ReadLimited reads all of r, failing with ErrTooLarge if it holds more than
+// limit bytes.
+func ReadLimited(r io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, ErrTooLarge
+	}
+	return data, nil
+}
+
+// ImportModList parses a mod-list.json, a save file or a zipped mods folder.
+func ImportModList(data []byte) (ModList, error) {
+	var list ModList
+	if !bytes.HasPrefix(data, []byte("PK")) {
+		err := json.Unmarshal(data, &list)
+		return list, err
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return list, err
+	}
+	for _, file := range archive.File {
+		switch path.Base(file.Name) {
+		case "level.dat0", "level.dat":
+			return ImportSave(file)
+		}
+	}
+	return ImportModsFolder(archive)
+}
+
+// ImportSave reads the mod list from the header of a save's level.dat.
+func ImportSave(file *zip.File) (ModList, error) {
+	var list ModList
+	reader, err := file.Open()
+	if err != nil {
+		return list, err
+	}
+	defer reader.Close()
+	raw, err := io.ReadAll(io.LimitReader(reader, saveHeaderLimit))
+	if err != nil {
+		return list, err
+	}
+
+	header := raw
+	if zr, err := zlib.NewReader(bytes.NewReader(raw)); err == nil {
+		header, _ = io.ReadAll(io.LimitReader(zr, saveHeaderLimit))
+		zr.Close()
+	}
+
+	list.Mods = ScanSaveMods(header)
+	if len(list.Mods) == 0 {
+		return list, errors.New("could not find the mod list in the save")
+	}
+	return list, nil
+}
+
+// ScanSaveMods searches a save header for the mod list, which always starts
+// with the base mod, and returns the first candidate that parses cleanly.
+func ScanSaveMods(header []byte) []ModListMod {
+	marker := []byte("\x04base")
+	for offset := 0; ; {
+		index := bytes.Index(header[offset:], marker)
+		if index < 0 {
+			return nil
+		}
+		start := offset + index
+		offset = start + 1
+		if start == 0 {
+			continue
+		}
+		for _, count := range saveModCounts(header[:start]) {
+			if mods, ok := parseSaveMods(header[start:], count); ok {
+				return mods
+			}
+		}
+	}
+}
+
+// saveModCounts returns the possible mod counts stored right before a mod list
+// candidate. Counts below 255 take one byte, larger ones are 0xFF followed by
+// a 32 bit count.
+func saveModCounts(before []byte) []int {
+	var counts []int
+	n := len(before)
+	if n >= 5 && before[n-5] == 0xFF {
+		if count := binary.LittleEndian.Uint32(before[n-4:]); count >= 0xFF && count <= 0xFFFF {
+			counts = append(counts, int(count))
+		}
+	}
+	if count := before[n-1]; count != 0 && count != 0xFF {
+		counts = append(counts, int(count))
+	}
+	return counts
+}
+
+// parseSaveMods reads count entries of name, version and CRC from a save
+// header. Strings and numbers use Factorio's space-optimized encoding.
+func parseSaveMods(data []byte, count int) ([]ModListMod, bool) {
+	r := &saveReader{data: data}
+	var mods []ModListMod
+	for range count {
+		length, ok := r.optimized(4)
+		if !ok || length == 0 || length > 100 {
+			return nil, false
+		}
+		name, ok := r.bytes(int(length))
+		if !ok || !isModName(name) {
+			return nil, false
+		}
+		var version [3]uint32
+		for i := range version {
+			if version[i], ok = r.optimized(2); !ok {
+				return nil, false
+			}
+		}
+		if _, ok := r.bytes(4); !ok { // CRC
+			return nil, false
+		}
+		mods = append(mods, ModListMod{
+			Name:    string(name),
+			Enabled: true,
+			Version: fmt.Sprintf("%d.%d.%d", version[0], version[1], version[2]),
+		})
+	}
+	return mods, true
+}
+
+type saveReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *saveReader) bytes(n int) ([]byte, bool) {
+	if r.pos+n > len(r.data) {
+		return nil, false
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b, true
+}
+
+// optimized reads a value stored in one byte, or 0xFF followed by size bytes.
+func (r *saveReader) optimized(size int) (uint32, bool) {
+	b, ok := r.bytes(1)
+	if !ok {
+		return 0, false
+	}
+	if b[0] != 0xFF {
+		return uint32(b[0]), true
+	}
+	b, ok = r.bytes(size)
+	if !ok {
+		return 0, false
+	}
+	if size == 2 {
+		return uint32(binary.LittleEndian.Uint16(b)), true
+	}
+	return binary.LittleEndian.Uint32(b), true
+}
+
+func isModName(name []byte) bool {
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == ' ') {
+			return false
+		}
+	}
+	return true
+}
+
+// ImportModsFolder reads the info.json of each mod in a zipped mods folder,
+// whether unpacked or as nested zips. A mod-list.json in the folder decides
+// which mods are enabled. Unreadable mods are skipped, but the import fails if
+// the files it reads exceed the size limits.
+func ImportModsFolder(archive *zip.Reader) (ModList, error) {
+	var list ModList
+	var enabled map[string]bool
+	extractor := &zipExtractor{remaining: maxExtractSize}
+	for _, file := range archive.File {
+		switch {
+		case path.Base(file.Name) == "mod-list.json":
+			var modList ModList
+			if err := extractor.readJson(file, &modList); err != nil {
+				return list, fmt.Errorf("parse %s: %w", file.Name, err)
+			}
+			enabled = map[string]bool{}
+			for _, mod := range modList.Mods {
+				enabled[mod.Name] = mod.Enabled
+			}
+		case path.Base(file.Name) == "info.json" && strings.Count(file.Name, "/") <= 2:
+			var info ModListMod
+			err := extractor.readJson(file, &info)
+			if errors.Is(err, ErrTooLarge) {
+				return list, err
+			}
+			if err == nil && info.Name != "" {
+				list.Mods = append(list.Mods, info)
+			}
+		case strings.HasSuffix(file.Name, ".zip"):
+			info, err := extractor.nestedModInfo(file)
+			if errors.Is(err, ErrTooLarge) {
+				return list, err
+			}
+			if err == nil {
+				list.Mods = append(list.Mods, info)
+			}
+		}
+	}
+	if len(list.Mods) == 0 {
+		return list, errors.New("no mods found in the archive")
+	}
+	for i := range list.Mods {
+		list.Mods[i].Enabled = enabled == nil || enabled[list.Mods[i].Name]
+	}
+	return list, nil
+}
+
+// zipExtractor reads files from an archive within a total size budget, so a
+// small upload can't decompress into unbounded memory.
+type zipExtractor struct {
+	remaining int64
+}
+
+// read returns the contents of file, failing with ErrTooLarge if it is larger
+// than limit or the remaining budget.
+func (extractor *zipExtractor) read(file *zip.File, limit int64) ([]byte, error) {
+	limit = min(limit, extractor.remaining)
+	if file.UncompressedSize64 > uint64(limit) {
+		return nil, fmt.Errorf("%s: %w", file.Name, ErrTooLarge)
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	data, err := ReadLimited(reader, limit)
+	if errors.Is(err, ErrTooLarge) {
+		return nil, fmt.Errorf("%s: %w", file.Name, err)
+	}
+	extractor.remaining -= int64(len(data))
+	return data, err
+}
+
+func (extractor *zipExtractor) readJson(file *zip.File, v any) error {
+	data, err := extractor.read(file, maxJsonSize)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// nestedModInfo reads the top-level info.json of a zipped mod.
+func (extractor *zipExtractor) nestedModInfo(file *zip.File) (ModListMod, error) {
+	var info ModListMod
+	data, err := extractor.read(file, extractor.remaining)
+	if err != nil {
+		return info, err
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return info, err
+	}
+	for _, inner := range archive.File {
+		if path.Base(inner.Name) == "info.json" && strings.Count(inner.Name, "/") == 1 {
+			err := extractor.readJson(inner, &info)
+			return info, err
+		}
+	}
+	return info, fmt.Errorf("no info.json in %s", file.Name)
+}
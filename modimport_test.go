@@ -0,0 +1,212 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// zipFiles builds a zip archive from file names and contents, in order.
+func zipFiles(t *testing.T, files ...string) []byte {
+	t.Helper()
+	return zipFilesMethod(t, zip.Deflate, files...)
+}
+
+// zipFilesMethod is zipFiles with a compression method, where zip.Store
+// builds a zipped mod as large as its contents.
+func zipFilesMethod(t *testing.T, method uint16, files ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for n := 0; n < len(files); n += 2 {
+		f, err := w.CreateHeader(&zip.FileHeader{Name: files[n], Method: method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(files[n+1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// saveHeader encodes a mod list the way a save's level.dat stores it, after
+// some unrelated header bytes.
+func saveHeader(mods []ModListMod) []byte {
+	header := []byte("\x00\x02\x00\x01\x00\x00\x00\x00\x03map")
+	if len(mods) < 0xFF {
+		header = append(header, byte(len(mods)))
+	} else {
+		header = append(header, 0xFF)
+		header = binary.LittleEndian.AppendUint32(header, uint32(len(mods)))
+	}
+	for _, mod := range mods {
+		header = append(header, byte(len(mod.Name)))
+		header = append(header, mod.Name...)
+		var version [3]uint16
+		fmt.Sscanf(mod.Version, "%d.%d.%d", &version[0], &version[1], &version[2])
+		for _, part := range version {
+			if part < 0xFF {
+				header = append(header, byte(part))
+			} else {
+				header = append(header, 0xFF)
+				header = binary.LittleEndian.AppendUint16(header, part)
+			}
+		}
+		header = append(header, 0xDE, 0xAD, 0xBE, 0xEF)
+	}
+	return append(header, "rest of the save"...)
+}
+
+func saveMods(count int) []ModListMod {
+	mods := []ModListMod{{Name: "base", Enabled: true, Version: "2.0.28"}}
+	for n := 1; n < count; n++ {
+		mods = append(mods, ModListMod{Name: fmt.Sprintf("mod-%d", n), Enabled: true, Version: fmt.Sprintf("1.%d.%d", n%7, n)})
+	}
+	return mods
+}
+
+func TestImportSave(t *testing.T) {
+	for _, count := range []int{1, 3, 254, 255, 300} {
+		t.Run(fmt.Sprint(count), func(t *testing.T) {
+			mods := saveMods(count)
+			list, err := ImportModList(zipFiles(t, "save/level.dat0", string(saveHeader(mods))))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(list.Mods, mods) {
+				t.Errorf("got %d mods, want %d", len(list.Mods), len(mods))
+			}
+		})
+	}
+}
+
+func TestImportModList(t *testing.T) {
+	nested := string(zipFiles(t, "nested-mod_1.0.0/info.json", `{"name": "nested-mod", "version": "1.0.0"}`))
+
+	tests := []struct {
+		name string
+		data []byte
+		mods []ModListMod
+	}{{
+		name: "mod-list.json",
+		data: []byte(`{"mods": [{"name": "base", "enabled": true}, {"name": "example-mod", "enabled": false}]}`),
+		mods: []ModListMod{{Name: "base", Enabled: true}, {Name: "example-mod"}},
+	}, {
+		name: "mods folder",
+		data: zipFiles(t,
+			"mods/mod-list.json", `{"mods": [{"name": "example-mod", "enabled": true}, {"name": "nested-mod", "enabled": false}]}`,
+			"mods/example-mod/info.json", `{"name": "example-mod", "version": "1.2.0"}`,
+			"mods/nested-mod_1.0.0.zip", nested,
+		),
+		mods: []ModListMod{{Name: "example-mod", Enabled: true, Version: "1.2.0"}, {Name: "nested-mod", Version: "1.0.0"}},
+	}, {
+		name: "broken nested mod",
+		data: zipFiles(t,
+			"mods/broken_1.0.0.zip", "not a zip",
+			"mods/nested-mod_1.0.0.zip", nested,
+		),
+		mods: []ModListMod{{Name: "nested-mod", Enabled: true, Version: "1.0.0"}},
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			list, err := ImportModList(test.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(list.Mods, test.mods) {
+				t.Errorf("mods = %+v, want %+v", list.Mods, test.mods)
+			}
+		})
+	}
+}
+
+func TestImportTooLarge(t *testing.T) {
+	defer func(extract, json int64) {
+		maxExtractSize, maxJsonSize = extract, json
+	}(maxExtractSize, maxJsonSize)
+	maxExtractSize, maxJsonSize = 4096, 256
+
+	// Highly compressible files stay small inside the archive.
+	padding := strings.Repeat(" ", 8192)
+	tests := []struct {
+		name string
+		data []byte
+	}{{
+		name: "info.json",
+		data: zipFiles(t, "mods/example-mod/info.json", `{"name": "example-mod"}`+padding[:512]),
+	}, {
+		name: "mod-list.json",
+		data: zipFiles(t, "mods/mod-list.json", `{"mods": []}`+padding[:512]),
+	}, {
+		name: "nested zip",
+		data: zipFiles(t, "mods/big_1.0.0.zip", string(zipFilesMethod(t, zip.Store, "big_1.0.0/info.json", `{"name": "big"}`, "big_1.0.0/data.lua", padding))),
+	}, {
+		name: "nested info.json",
+		data: zipFiles(t, "mods/big_1.0.0.zip", string(zipFiles(t, "big_1.0.0/info.json", `{"name": "big"}`+padding[:512]))),
+	}, {
+		name: "total",
+		data: zipFiles(t,
+			"mods/a/info.json", `{"name": "a"}`+padding[:200],
+			"mods/b/info.json", `{"name": "b"}`+padding[:200],
+			"mods/c/info.json", `{"name": "c"}`+padding[:200],
+			"mods/big_1.0.0.zip", string(zipFilesMethod(t, zip.Store, "big_1.0.0/info.json", `{"name": "big"}`, "big_1.0.0/data.lua", padding[:3500])),
+		),
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if len(test.data) > int(maxExtractSize) {
+				t.Fatalf("archive is %d bytes, the limit applies to its contents", len(test.data))
+			}
+			_, err := ImportModList(test.data)
+			if !errors.Is(err, ErrTooLarge) {
+				t.Errorf("err = %v, want ErrTooLarge", err)
+			}
+		})
+	}
+}
+
+func TestAttachmentTooLarge(t *testing.T) {
+	fake := setupTest(t)
+	data := discordgo.ApplicationCommandInteractionData{
+		Resolved: &discordgo.ApplicationCommandInteractionDataResolved{
+			Attachments: map[string]*discordgo.MessageAttachment{
+				"1": {URL: fake.URL + "/api/mods", Size: int(maxImportSize) + 1},
+			},
+		},
+	}
+	option := &discordgo.ApplicationCommandInteractionDataOption{Name: "file", Type: discordgo.ApplicationCommandOptionAttachment, Value: "1"}
+
+	m := &recordingMessenger{}
+	if _, ok := AttachmentModList(m, commandInteraction("modpack"), data, option); ok {
+		t.Fatal("oversized attachment was accepted")
+	}
+	if embed := respondedEmbed(t, m); embed.Title != "ERROR: Attachment Too Large" {
+		t.Errorf("title = %q", embed.Title)
+	}
+	if fake.Requests("/api/mods") != 0 {
+		t.Error("oversized attachment was downloaded")
+	}
+
+	// The download itself is limited too, whatever size Discord reports.
+	defer func(size int64) { maxImportSize = size }(maxImportSize)
+	maxImportSize = 16
+	data.Resolved.Attachments["1"].Size = 1
+	m = &recordingMessenger{}
+	if _, ok := AttachmentModList(m, commandInteraction("modpack"), data, option); ok {
+		t.Fatal("oversized download was accepted")
+	}
+	if embed := respondedEmbed(t, m); embed.Title != "ERROR: Attachment Too Large" {
+		t.Errorf("title = %q", embed.Title)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// modpackRequests bounds how many full mods a modpack check requests at once.
+const modpackRequests = 8
+
+// ModpackReport lists the problems found in a mod list, one formatted line per
+// problem.
+type ModpackReport struct {
+	Missing      []string
+	Unchecked    []string
+	Outdated     []string
+	Newer        []string
+	Unsatisfied  []string
+	Incompatible []string
+}
+
+func (report ModpackReport) OK() bool {
+	return len(report.Missing)+len(report.Unchecked)+len(report.Outdated)+len(report.Newer)+len(report.Unsatisfied)+len(report.Incompatible) == 0
+}
+
+// CheckModpack checks the enabled mods of a mod list against their full portal
+// details. Each mod is checked at its listed version, or else at its latest
+// release for the target Factorio version, or else at its latest release.
+func CheckModpack(list ModList, factorioVersion string) ModpackReport {
+	var report ModpackReport
+
+	enabled := map[string]bool{"base": true}
+	installed := map[string]string{}
+	for _, listMod := range list.Mods {
+		if listMod.Enabled {
+			enabled[listMod.Name] = true
+			if listMod.Version != "" {
+				installed[listMod.Name] = listMod.Version
+			}
+		}
+	}
+
+	var names []string
+	for _, name := range SortedKeys(enabled) {
+		if vanillaMods[name] {
+			continue
+		}
+		if mods[name] == nil {
+			report.Missing = append(report.Missing, fmt.Sprintf("- %s", name))
+			continue
+		}
+		names = append(names, name)
+	}
+
+	fullMods := RequestFullMods(names)
+	selected := map[string]*Release{}
+	for _, name := range names {
+		fullMod, ok := fullMods[name]
+		if !ok {
+			report.Unchecked = append(report.Unchecked, fmt.Sprintf("- [%s](%s)", mods[name].Title, mods[name].URL()))
+			continue
+		}
+		if release := fullMod.ModpackRelease(installed[name], factorioVersion); release != nil {
+			selected[name] = release
+		}
+
+		if fullMod.ReleaseFor(factorioVersion) != nil {
+			continue
+		}
+		if older := fullMod.NewestReleaseBefore(factorioVersion); older != nil {
+			report.Outdated = append(report.Outdated, fmt.Sprintf("- [%s](%s) (Factorio %s)", fullMod.Title, fullMod.URL(), older.FactorioVersion()))
+		} else if oldest := fullMod.OldestFactorioVersion(); oldest != "" {
+			report.Newer = append(report.Newer, fmt.Sprintf("- [%s](%s) (Factorio %s)", fullMod.Title, fullMod.URL(), oldest))
+		}
+	}
+
+	for _, name := range names {
+		release := selected[name]
+		if release == nil {
+			continue
+		}
+		for _, dependency := range ParseDependencies(release.InfoJson.Dependencies) {
+			present := enabled[dependency.Name]
+			switch {
+			case dependency.Kind == DependencyIncompatible:
+				if present {
+					report.Incompatible = append(report.Incompatible, fmt.Sprintf("- %s is incompatible with %s", name, dependency.Name))
+				}
+			case !present:
+				if dependency.IsRequired() {
+					report.Unsatisfied = append(report.Unsatisfied, fmt.Sprintf("- %s requires %s", name, DependencyString(dependency)))
+				}
+			case vanillaMods[dependency.Name] || mods[dependency.Name] == nil:
+				// Vanilla mods follow the game version and missing mods are already reported.
+			default:
+				version, ok := installed[dependency.Name]
+				if !ok {
+					version = mods[dependency.Name].LatestRelease.Version
+					if release := selected[dependency.Name]; release != nil {
+						version = release.Version
+					}
+				}
+				if !dependency.Satisfied(version) {
+					report.Unsatisfied = append(report.Unsatisfied, fmt.Sprintf("- %s requires %s, found %s", name, DependencyString(dependency), version))
+				}
+			}
+		}
+	}
+
+	return report
+}
+
+// RequestFullMods requests the full details of the named mods, a few at a
+// time. Mods that could not be requested are left out.
+func RequestFullMods(names []string) map[string]FullMod {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	fullMods := map[string]FullMod{}
+	limit := make(chan struct{}, modpackRequests)
+	for _, name := range names {
+		mod := mods[name]
+		if mod == nil {
+			continue
+		}
+		wg.Add(1)
+		limit <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-limit }()
+			fullMod, err := mod.Request(true)
+			if err != nil {
+				slog.Warn("Could not request mod", "mod", mod.Name, "err", err)
+				return
+			}
+			mu.Lock()
+			fullMods[mod.Name] = fullMod
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return fullMods
+}
+
+// DependencyString formats the dependency name with its version constraint.
+func DependencyString(dependency Dependency) string {
+	if constraint := dependency.Constraint(); constraint != "" {
+		return fmt.Sprintf("%s %s", dependency.Name, constraint)
+	}
+	return dependency.Name
+}
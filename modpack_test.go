@@ -0,0 +1,86 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckModpack(t *testing.T) {
+	setupTest(t)
+	list, err := portal.ListMods()
+	if err != nil {
+		t.Fatal(err)
+	}
+	CacheModList(list.Results)
+	// Listed on the portal, but its details can't be requested.
+	mods["ghost-mod"] = &Mod{Name: "ghost-mod", Title: "Ghost Mod"}
+
+	url := func(name string) string { return config.PortalURL + "/mod/" + name }
+	tests := []struct {
+		name            string
+		mods            []ModListMod
+		factorioVersion string
+		report          ModpackReport
+	}{{
+		name:            "ok",
+		mods:            []ModListMod{{Name: "example-mod", Enabled: true}, {Name: "example-lib", Enabled: true}},
+		factorioVersion: "2.0",
+	}, {
+		name:            "older factorio version",
+		mods:            []ModListMod{{Name: "example-mod", Enabled: true}, {Name: "example-lib", Enabled: true}},
+		factorioVersion: "1.1",
+	}, {
+		name:            "newer than factorio version",
+		mods:            []ModListMod{{Name: "example-mod", Enabled: true}, {Name: "example-lib", Enabled: true}},
+		factorioVersion: "1.0",
+		report: ModpackReport{Newer: []string{
+			"- [Example Library](" + url("example-lib") + ") (Factorio 1.1)",
+			"- [Example Mod](" + url("example-mod") + ") (Factorio 1.1)",
+		}},
+	}, {
+		name:            "outdated and incompatible",
+		mods:            []ModListMod{{Name: "example-mod", Enabled: true}, {Name: "example-lib", Enabled: true}, {Name: "old-mod", Enabled: true}},
+		factorioVersion: "2.0",
+		report: ModpackReport{
+			Outdated:     []string{"- [Old Mod](" + url("old-mod") + ") (Factorio 1.1)"},
+			Incompatible: []string{"- example-mod is incompatible with old-mod"},
+		},
+	}, {
+		name:            "disabled mods are ignored",
+		mods:            []ModListMod{{Name: "example-mod", Enabled: true}, {Name: "example-lib", Enabled: true}, {Name: "old-mod", Enabled: false}},
+		factorioVersion: "2.0",
+	}, {
+		name:            "missing dependency",
+		mods:            []ModListMod{{Name: "example-mod", Enabled: true}},
+		factorioVersion: "2.0",
+		report:          ModpackReport{Unsatisfied: []string{"- example-mod requires example-lib >= 1.1.0"}},
+	}, {
+		name:            "listed version too old",
+		mods:            []ModListMod{{Name: "example-mod", Enabled: true}, {Name: "example-lib", Enabled: true, Version: "1.0.0"}},
+		factorioVersion: "2.0",
+		report:          ModpackReport{Unsatisfied: []string{"- example-mod requires example-lib >= 1.1.0, found 1.0.0"}},
+	}, {
+		name:            "listed version of dependent",
+		mods:            []ModListMod{{Name: "example-mod", Enabled: true, Version: "1.0.0"}, {Name: "example-lib", Enabled: true, Version: "1.0.0"}},
+		factorioVersion: "2.0",
+	}, {
+		name:            "missing and unchecked",
+		mods:            []ModListMod{{Name: "no-such-mod", Enabled: true}, {Name: "ghost-mod", Enabled: true}},
+		factorioVersion: "2.0",
+		report: ModpackReport{
+			Missing:   []string{"- no-such-mod"},
+			Unchecked: []string{"- [Ghost Mod](" + url("ghost-mod") + ")"},
+		},
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			report := CheckModpack(ModList{Mods: test.mods}, test.factorioVersion)
+			if !reflect.DeepEqual(report, test.report) {
+				t.Errorf("report = %+v\nwant %+v", report, test.report)
+			}
+			if report.OK() != reflect.DeepEqual(test.report, ModpackReport{}) {
+				t.Errorf("OK() = %v", report.OK())
+			}
+		})
+	}
+}
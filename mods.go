@@ -1,11 +1,8 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
-	"regexp"
+	"maps"
 	"slices"
 	"strconv"
 	"strings"
@@ -25,6 +22,7 @@ type Mod struct {
 	Summary        string          `json:"summary"`
 	DownloadsCount int             `json:"downloads_count"`
 	Category       string          `json:"category"`
+	Tags           []string        `json:"tags"`
 	LatestRelease  Release         `json:"latest_release"`
 	Dependencies   map[string]bool `json:"dependencies"`
 }
@@ -56,6 +54,7 @@ type ModList struct {
 type ModListMod struct {
 	Name    string `json:"name"`
 	Enabled bool   `json:"enabled"`
+	Version string `json:"version,omitempty"`
 }
 
 var allowedSourceURLs = []string{
@@ -73,11 +72,25 @@ func isAllowedSourceURL(url string) bool {
 }
 
 func (mod Mod) URL() string {
-	return fmt.Sprintf("https://mods.factorio.com/mod/%s", strings.Replace(mod.Name, " ", "%20", -1))
+	return fmt.Sprintf("%s/mod/%s", config.PortalURL, strings.Replace(mod.Name, " ", "%20", -1))
+}
+
+// Categories returns the mod's category followed by its tags.
+func (mod Mod) Categories() []string {
+	if mod.Category == "" {
+		return mod.Tags
+	}
+	return append([]string{mod.Category}, mod.Tags...)
 }
 
 func (mod Mod) FactorioVersion() string {
-	version := mod.LatestRelease.InfoJson.FactorioVersion
+	return mod.LatestRelease.FactorioVersion()
+}
+
+// FactorioVersion returns the release's Factorio version with leading zeros
+// removed, e.g. "1.01" becomes "1.1".
+func (release Release) FactorioVersion() string {
+	version := release.InfoJson.FactorioVersion
 	if version == "" {
 		return ""
 	}
@@ -94,133 +107,190 @@ func (mod Mod) FactorioVersion() string {
 }
 
 func (mod Mod) Request(full bool) (FullMod, error) {
-	url := fmt.Sprintf("https://mods.factorio.com/api/mods/%s", strings.Replace(mod.Name, " ", "%20", -1))
+	var fullMod FullMod
+	var err error
 	if full {
-		url += "/full"
+		if cached, ok := modCache.Get(mod.Name); ok {
+			cached.LatestRelease = mod.LatestRelease
+			return cached, nil
+		}
+		fullMod, err = portal.GetFullMod(mod.Name)
+	} else {
+		fullMod, err = portal.GetMod(mod.Name)
 	}
-
-	var fullMod FullMod
-	resp, err := http.Get(url)
 	if err != nil {
 		return fullMod, err
 	}
-	defer resp.Body.Close()
+	// The cache compares LatestRelease with the mod list, so set it first.
+	// Details that lag behind the mod list aren't cached, so the missing
+	// release is fetched again on the next request.
+	fullMod.LatestRelease = mod.LatestRelease
+	if full {
+		if fullMod.GetRelease(mod.LatestRelease.Version) != nil {
+			modCache.Put(fullMod)
+		}
+		dependencyIndex.Record(fullMod)
+	}
+	return fullMod, nil
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fullMod, err
+// Clone returns a deep copy of the mod that shares no slices or maps with it.
+func (mod FullMod) Clone() FullMod {
+	if mod.Mod != nil {
+		inner := *mod.Mod
+		inner.Tags = slices.Clone(inner.Tags)
+		inner.Dependencies = maps.Clone(inner.Dependencies)
+		inner.LatestRelease = inner.LatestRelease.Clone()
+		mod.Mod = &inner
+	}
+	mod.Releases = slices.Clone(mod.Releases)
+	for i, release := range mod.Releases {
+		mod.Releases[i] = release.Clone()
 	}
+	return mod
+}
 
-	err = json.Unmarshal(body, &fullMod)
-	fullMod.LatestRelease = mod.LatestRelease
-	return fullMod, err
+func (release Release) Clone() Release {
+	release.InfoJson.Dependencies = slices.Clone(release.InfoJson.Dependencies)
+	return release
 }
 
 func (mod FullMod) GetThumbnail() string {
 	if mod.Thumbnail == "" || mod.Thumbnail == "/assets/.thumb.png" {
 		return ""
 	}
-	return "https://assets-mod.factorio.com" + mod.Thumbnail
+	return config.AssetsURL + mod.Thumbnail
 }
 
 func (mod FullMod) GetRelease(version string) *Release {
 	for _, release := range mod.Releases {
-		if release.Version == version {
+		if CompareVersions(release.Version, version) == 0 {
 			return &release
 		}
 	}
 	return nil
 }
 
-func (mod FullMod) FormatChangelog(version string) string {
-	parts := strings.Split(mod.Changelog, strings.Repeat("-", 99))
-	if len(parts) == 1 {
-		return ""
+// NewestRelease returns the release with the highest version, or nil if the
+// mod has no releases.
+func (mod FullMod) NewestRelease() *Release {
+	var newest *Release
+	for i, release := range mod.Releases {
+		if newest == nil || CompareVersions(release.Version, newest.Version) > 0 {
+			newest = &mod.Releases[i]
+		}
 	}
-	for _, part := range parts {
-		part = strings.ReplaceAll(part, "\r", "")
-		part = strings.ReplaceAll(part, "__", "\\__")
-		i := strings.Index(part, "Version: ")
-		if i == -1 {
+	return newest
+}
+
+// ReleaseFor returns the newest release for a Factorio version, or nil if the
+// mod has none.
+func (mod FullMod) ReleaseFor(factorioVersion string) *Release {
+	var newest *Release
+	for i, release := range mod.Releases {
+		if release.FactorioVersion() != factorioVersion {
 			continue
 		}
-		i = i + len("Version: ")
-		j := strings.Index(part[i:], "\n")
-		if j == -1 {
-			continue
+		if newest == nil || CompareVersions(release.Version, newest.Version) > 0 {
+			newest = &mod.Releases[i]
 		}
-		if part[i:i+j] != version {
+	}
+	return newest
+}
+
+// NewestReleaseBefore returns the newest release for a Factorio version older
+// than factorioVersion, or nil if the mod has none.
+func (mod FullMod) NewestReleaseBefore(factorioVersion string) *Release {
+	var newest *Release
+	for i, release := range mod.Releases {
+		if CompareVersions(release.FactorioVersion(), factorioVersion) >= 0 {
 			continue
 		}
-		part = part[i+j+1:]
-		i = strings.Index(part, "Date: ")
-		if i != -1 {
-			j = strings.Index(part[i:], "\n")
-			part = part[i+j+1:]
+		if newest == nil || CompareVersions(release.Version, newest.Version) > 0 {
+			newest = &mod.Releases[i]
 		}
+	}
+	return newest
+}
 
-		lines := strings.Split(part, "\n")
-		for i, line := range lines {
-			if strings.TrimSpace(line) == "" {
-				lines[i] = ""
-				continue
-			}
-			re := regexp.MustCompile(`^\s{0,4}`)
-			line = re.ReplaceAllString(line, "")
-			l := len(line)
-			if line[:1] != " " && line[l-1:] == ":" {
-				line = "**" + line + "**"
-			}
-			lines[i] = line
-		}
-		part = strings.Join(lines, "\n")
-		re := regexp.MustCompile(`\n+`)
-		part = re.ReplaceAllString(part, "\n")
-
-		if isAllowedSourceURL(mod.SourceURL) {
-			issues := regexp.MustCompile(`#[0-9]+`)
-			part = issues.ReplaceAllStringFunc(part, func(match string) string {
-				return fmt.Sprintf("[%s](%s/issues/%s)", match, mod.SourceURL, match[1:])
-			})
-			pulls := regexp.MustCompile(`![0-9]+`)
-			part = pulls.ReplaceAllStringFunc(part, func(match string) string {
-				return fmt.Sprintf("[%s](%s/pulls/%s)", match, mod.SourceURL, match[1:])
-			})
+// OldestFactorioVersion returns the oldest Factorio version the mod has a
+// release for.
+func (mod FullMod) OldestFactorioVersion() string {
+	var oldest string
+	for _, release := range mod.Releases {
+		version := release.FactorioVersion()
+		if oldest == "" || CompareVersions(version, oldest) < 0 {
+			oldest = version
 		}
-		return Truncate(part, 4096)
 	}
-	return ""
+	return oldest
 }
 
-func BulkRequest(url string) (Response, error) {
-	var data Response
-	resp, err := http.Get(url)
-	if err != nil {
-		return data, err
+// ModpackRelease returns the release a mod list uses: the listed version if
+// the portal has it, else the newest release for the Factorio version, else
+// the newest release.
+func (mod FullMod) ModpackRelease(version, factorioVersion string) *Release {
+	if version != "" {
+		for i, release := range mod.Releases {
+			if CompareVersions(release.Version, version) == 0 {
+				return &mod.Releases[i]
+			}
+		}
 	}
-	defer resp.Body.Close()
+	if release := mod.ReleaseFor(factorioVersion); release != nil {
+		return release
+	}
+	return mod.NewestRelease()
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return data, err
+// ChangelogEntries parses the mod's changelog.
+func (mod FullMod) ChangelogEntries() []ChangelogEntry {
+	return ParseChangelog(mod.Changelog)
+}
+
+// IssueURL returns the source URL used to link issue references in
+// changelogs, or "" if the source isn't a supported forge.
+func (mod FullMod) IssueURL() string {
+	if isAllowedSourceURL(mod.SourceURL) {
+		return mod.SourceURL
 	}
+	return ""
+}
 
-	err = json.Unmarshal(body, &data)
-	if err != nil {
-		return data, err
+func (mod FullMod) FormatChangelog(version string) string {
+	for _, entry := range mod.ChangelogEntries() {
+		if CompareVersions(entry.Version, version) == 0 {
+			return Truncate(FormatChangelogCategories(entry.Categories, mod.IssueURL()), 4096)
+		}
 	}
-	return data, nil
+	return ""
 }
 
-func VersionFilter(option *discordgo.ApplicationCommandInteractionDataOption) []*Mod {
-	if option == nil {
-		return versions[defaultVersion]
+func VersionFilter(option *discordgo.ApplicationCommandInteractionDataOption, defaultVersion string) []*Mod {
+	if option != nil {
+		if modArr, ok := versions[option.StringValue()]; ok {
+			return modArr
+		}
 	}
-	modArr, ok := versions[option.StringValue()]
-	if !ok {
-		return versions[defaultVersion]
+	if modArr, ok := versions[defaultVersion]; ok {
+		return modArr
 	}
-	return modArr
+	return versions[config.DefaultVersion]
+}
+
+// FactorioVersions returns every Factorio version seen in the mod list, newest
+// first.
+func FactorioVersions() []string {
+	var versionArr []string
+	for version := range versions {
+		if version != "all" {
+			versionArr = append(versionArr, version)
+		}
+	}
+	slices.SortFunc(versionArr, func(a, b string) int {
+		return CompareVersions(b, a)
+	})
+	return versionArr
 }
 
 func AuthorFilter(modArr []*Mod, option *discordgo.ApplicationCommandInteractionDataOption) []*Mod {
@@ -289,15 +359,28 @@ func ModAutocomplete(modArr []*Mod, value string) []*Mod {
 	return titleFirst
 }
 
+// CategoryAutocomplete returns known categories and tags containing value,
+// most common first.
+func CategoryAutocomplete(value string) []string {
+	value = strings.ToLower(value)
+	var categoryArr []string
+	for category := range categories {
+		if strings.Contains(category, value) {
+			categoryArr = append(categoryArr, category)
+		}
+	}
+	slices.SortFunc(categoryArr, func(a, b string) int {
+		return categories[b] - categories[a]
+	})
+	if len(categoryArr) > 25 {
+		return categoryArr[:25]
+	}
+	return categoryArr
+}
+
 func VersionSort(modArr []*Mod) []*Mod {
 	slices.SortStableFunc(modArr, func(a, b *Mod) int {
-		aV, bV := a.FactorioVersion(), b.FactorioVersion()
-		if aV > bV {
-			return -1
-		} else if aV < bV {
-			return 1
-		}
-		return 0
+		return CompareVersions(b.FactorioVersion(), a.FactorioVersion())
 	})
 	return modArr
 }
\ No newline at end of file
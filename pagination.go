@@ -0,0 +1,273 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	pagePrefix = "page"
+	// pageExpiry is how long page buttons keep working after the last click.
+	pageExpiry = 15 * time.Minute
+	// storedPager serves pages kept by RespondStoredPages.
+	storedPager = "stored"
+	// storedArgsPrefix marks a key into pageArgs in place of pager arguments.
+	storedArgsPrefix = "@"
+)
+
+// Pager rebuilds the pages of a paginated response from the arguments stored
+// in its custom IDs. Arguments that don't fit in a custom ID are kept in
+// pageArgs instead.
+type Pager func(i *discordgo.InteractionCreate, args []string) ([]*discordgo.MessageEmbed, error)
+
+// ErrPagesExpired is returned by pagers whose stored state has expired. The
+// navigation is removed as if the buttons themselves had expired.
+var ErrPagesExpired = errors.New("pages expired")
+
+var pagers = map[string]Pager{storedPager: StoredPages}
+
+// pageArgs and storedPages hold page state that doesn't fit in custom IDs.
+var (
+	pageArgs    = NewPageStore[[]string]()
+	storedPages = NewPageStore[[]*discordgo.MessageEmbed]()
+)
+
+func RegisterPager(name string, pager Pager) {
+	pagers[name] = pager
+}
+
+// PageStore keeps state for page buttons under short random keys. Entries
+// expire like the buttons referring to them.
+type PageStore[T any] struct {
+	mu      sync.Mutex
+	entries map[string]*pageStoreEntry[T]
+}
+
+type pageStoreEntry[T any] struct {
+	value  T
+	expiry time.Time
+}
+
+func NewPageStore[T any]() *PageStore[T] {
+	return &PageStore[T]{entries: map[string]*pageStoreEntry[T]{}}
+}
+
+// Put stores value under a new key, pruning expired entries.
+func (store *PageStore[T]) Put(value T) string {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	now := time.Now()
+	for key, entry := range store.entries {
+		if now.After(entry.expiry) {
+			delete(store.entries, key)
+		}
+	}
+	b := make([]byte, 9)
+	rand.Read(b)
+	key := base64.RawURLEncoding.EncodeToString(b)
+	store.entries[key] = &pageStoreEntry[T]{value: value, expiry: now.Add(pageExpiry)}
+	return key
+}
+
+// Get returns the value stored under key and extends its expiry, since every
+// click extends the expiry of the buttons.
+func (store *PageStore[T]) Get(key string) (T, bool) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	entry, ok := store.entries[key]
+	if !ok || time.Now().After(entry.expiry) {
+		var zero T
+		return zero, false
+	}
+	entry.expiry = time.Now().Add(pageExpiry)
+	return entry.value, true
+}
+
+// PageID encodes a page target, expiry and encoded pager arguments as a
+// custom ID. It returns false if the ID is too long.
+func PageID(pager, target string, expiry time.Time, args []string) (string, bool) {
+	parts := append([]string{pagePrefix, pager, target, strconv.FormatInt(expiry.Unix(), 10)}, args...)
+	id := strings.Join(parts, "|")
+	return id, len(id) <= 100
+}
+
+// EncodePageArgs returns the pager arguments as they are stored in custom IDs.
+// Arguments that don't fit, contain the separator or could be mistaken for a
+// key are replaced by a key into pageArgs.
+func EncodePageArgs(pager string, args []string) []string {
+	fits := !slices.ContainsFunc(args, func(arg string) bool {
+		return strings.Contains(arg, "|") || strings.HasPrefix(arg, storedArgsPrefix)
+	})
+	if fits {
+		_, fits = PageID(pager, "jump", time.Now().Add(pageExpiry), args)
+	}
+	if fits {
+		return args
+	}
+	return []string{storedArgsPrefix + pageArgs.Put(args)}
+}
+
+// DecodePageArgs reverses EncodePageArgs, returning false if the arguments
+// were stored and have expired.
+func DecodePageArgs(args []string) ([]string, bool) {
+	if len(args) != 1 || !strings.HasPrefix(args[0], storedArgsPrefix) {
+		return args, true
+	}
+	return pageArgs.Get(strings.TrimPrefix(args[0], storedArgsPrefix))
+}
+
+// PageComponents returns the Prev/Next buttons and a jump menu for the given
+// page, or nil if the encoded pager arguments don't fit.
+func PageComponents(pager string, args []string, page, count int) []discordgo.MessageComponent {
+	expiry := time.Now().Add(pageExpiry)
+	prevID, ok := PageID(pager, strconv.Itoa(page-1), expiry, args)
+	if !ok {
+		return nil
+	}
+	nextID, _ := PageID(pager, strconv.Itoa(page+1), expiry, args)
+	jumpID, _ := PageID(pager, "jump", expiry, args)
+
+	components := []discordgo.MessageComponent{discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+		discordgo.Button{Label: "Prev", Style: discordgo.SecondaryButton, CustomID: prevID, Disabled: page == 0},
+		discordgo.Button{Label: "Next", Style: discordgo.SecondaryButton, CustomID: nextID, Disabled: page == count-1},
+	}}}
+	if count > 2 {
+		start := max(0, min(page-12, count-25))
+		var options []discordgo.SelectMenuOption
+		for n := start; n < count && n < start+25; n++ {
+			options = append(options, discordgo.SelectMenuOption{
+				Label:   fmt.Sprintf("Page %d", n+1),
+				Value:   strconv.Itoa(n),
+				Default: n == page,
+			})
+		}
+		components = append(components, discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.SelectMenu{CustomID: jumpID, Placeholder: "Jump to page", Options: options},
+		}})
+	}
+	return components
+}
+
+// pageData renders one page with navigation for the encoded pager arguments.
+func pageData(pager string, args []string, pages []*discordgo.MessageEmbed, page int) *discordgo.InteractionResponseData {
+	page = max(0, min(page, len(pages)-1))
+	embed := pages[page]
+	components := []discordgo.MessageComponent{}
+	if len(pages) > 1 {
+		embed.Footer = &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("Page %d/%d", page+1, len(pages))}
+		if pageComponents := PageComponents(pager, args, page, len(pages)); pageComponents != nil {
+			components = pageComponents
+		}
+	}
+	return &discordgo.InteractionResponseData{
+		Embeds:     []*discordgo.MessageEmbed{embed},
+		Components: components,
+	}
+}
+
+// RespondPages responds with one page of a paginated response, adding
+// navigation if there is more than one page.
+func RespondPages(m Messenger, i *discordgo.InteractionCreate, pager string, args []string, pages []*discordgo.MessageEmbed, page int) {
+	if len(pages) > 1 {
+		args = EncodePageArgs(pager, args)
+	}
+	err := m.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: pageData(pager, args, pages, page),
+	})
+	if err != nil {
+		slog.Error("Could not respond to interaction", "err", err)
+	}
+}
+
+// HandlePageComponent rebuilds the pages for a clicked page button or jump
+// menu and updates the message. Expired navigation is removed instead.
+func HandlePageComponent(m Messenger, i *discordgo.InteractionCreate, data discordgo.MessageComponentInteractionData) {
+	parts := strings.Split(data.CustomID, "|")
+	if len(parts) < 4 || parts[0] != pagePrefix {
+		return
+	}
+	name, target, args := parts[1], parts[2], parts[4:]
+	if target == "jump" && len(data.Values) > 0 {
+		target = data.Values[0]
+	}
+	page, _ := strconv.Atoi(target)
+	expiry, _ := strconv.ParseInt(parts[3], 10, 64)
+
+	resp := &discordgo.InteractionResponse{Type: discordgo.InteractionResponseUpdateMessage}
+	expired := &discordgo.InteractionResponseData{Embeds: i.Message.Embeds, Components: []discordgo.MessageComponent{}}
+	pager, ok := pagers[name]
+	pagerArgs, stored := DecodePageArgs(args)
+	if !ok || !stored || time.Now().Unix() > expiry {
+		resp.Data = expired
+	} else {
+		pages, err := pager(i, pagerArgs)
+		switch {
+		case errors.Is(err, ErrPagesExpired):
+			resp.Data = expired
+		case err != nil || len(pages) == 0:
+			slog.Error("Could not build pages", "pager", name, "err", err)
+			RespondDefaultError(m, i)
+			return
+		default:
+			resp.Data = pageData(name, args, pages, page)
+		}
+	}
+	if err := m.InteractionRespond(i.Interaction, resp); err != nil {
+		slog.Error("Could not respond to interaction", "err", err)
+	}
+}
+
+// RespondStoredPages responds like RespondPages for pages that can't be
+// rebuilt later, keeping them in memory while their buttons work.
+func RespondStoredPages(m Messenger, i *discordgo.InteractionCreate, pages []*discordgo.MessageEmbed, page int) {
+	var args []string
+	if len(pages) > 1 {
+		args = []string{storedPages.Put(pages)}
+	}
+	RespondPages(m, i, storedPager, args, pages, page)
+}
+
+// StoredPages is the pager for RespondStoredPages. It returns copies so
+// concurrent clicks don't share the embeds.
+func StoredPages(i *discordgo.InteractionCreate, args []string) ([]*discordgo.MessageEmbed, error) {
+	if len(args) != 1 {
+		return nil, ErrPagesExpired
+	}
+	pages, ok := storedPages.Get(args[0])
+	if !ok {
+		return nil, ErrPagesExpired
+	}
+	copies := make([]*discordgo.MessageEmbed, len(pages))
+	for n, page := range pages {
+		embed := *page
+		copies[n] = &embed
+	}
+	return copies, nil
+}
+
+// PageEmbeds splits lines into embeds of at most max characters. The first
+// embed is a copy of first; the rest share its color.
+func PageEmbeds(first discordgo.MessageEmbed, lines []string, max int) []*discordgo.MessageEmbed {
+	chunks := SplitLines(lines, max)
+	if len(chunks) == 0 {
+		chunks = []string{first.Description}
+	}
+	embeds := make([]*discordgo.MessageEmbed, len(chunks))
+	for n, chunk := range chunks {
+		embeds[n] = &discordgo.MessageEmbed{Title: first.Title, URL: first.URL, Description: chunk, Color: first.Color}
+	}
+	embeds[0] = &first
+	embeds[0].Description = chunks[0]
+	return embeds
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// echoPages registers a pager whose pages show the arguments it was given.
+func echoPages(t *testing.T) {
+	t.Helper()
+	RegisterPager("echo", func(i *discordgo.InteractionCreate, args []string) ([]*discordgo.MessageEmbed, error) {
+		var pages []*discordgo.MessageEmbed
+		for n := range 3 {
+			pages = append(pages, &discordgo.MessageEmbed{Description: fmt.Sprintf("%d:%s", n, strings.Join(args, ","))})
+		}
+		return pages, nil
+	})
+	t.Cleanup(func() { delete(pagers, "echo") })
+}
+
+func pageInteraction(response *discordgo.InteractionResponse, customID string) (*discordgo.InteractionCreate, discordgo.MessageComponentInteractionData) {
+	data := discordgo.MessageComponentInteractionData{CustomID: customID}
+	return &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		Type:    discordgo.InteractionMessageComponent,
+		GuildID: "guild",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "user"}},
+		Message: &discordgo.Message{Embeds: response.Data.Embeds},
+		Data:    data,
+	}}, data
+}
+
+// pageButton returns the custom ID of the Prev or Next button.
+func pageButton(t *testing.T, response *discordgo.InteractionResponse, label string) string {
+	t.Helper()
+	if len(response.Data.Components) == 0 {
+		t.Fatal("response has no page components")
+	}
+	for _, component := range response.Data.Components[0].(discordgo.ActionsRow).Components {
+		if button := component.(discordgo.Button); button.Label == label {
+			return button.CustomID
+		}
+	}
+	t.Fatalf("no %s button", label)
+	return ""
+}
+
+func TestPageArgs(t *testing.T) {
+	echoPages(t)
+
+	tests := []struct {
+		name   string
+		args   []string
+		stored bool
+	}{
+		{"short", []string{"example-mod", "1.0.0"}, false},
+		{"none", nil, false},
+		{"long", []string{strings.Repeat("a", 60), strings.Repeat("b", 60)}, true},
+		{"separator", []string{"a|b"}, true},
+		{"key prefix", []string{"@route"}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pages, _ := pagers["echo"](nil, test.args)
+			m := &recordingMessenger{}
+			RespondPages(m, commandInteraction("echo"), "echo", test.args, pages, 0)
+
+			next := pageButton(t, m.Responses[0], "Next")
+			if len(next) > 100 {
+				t.Errorf("custom ID is %d characters long", len(next))
+			}
+			if stored := strings.Contains(next, "|"+storedArgsPrefix); stored != test.stored {
+				t.Errorf("stored = %v, want %v", stored, test.stored)
+			}
+
+			// Clicking twice keeps working with the same arguments.
+			for n := 1; n <= 2; n++ {
+				i, data := pageInteraction(m.Responses[len(m.Responses)-1], next)
+				HandlePageComponent(m, i, data)
+				response := m.Responses[len(m.Responses)-1]
+				if want := fmt.Sprintf("%d:%s", n, strings.Join(test.args, ",")); response.Data.Embeds[0].Description != want {
+					t.Fatalf("click %d: page %q, want %q", n, response.Data.Embeds[0].Description, want)
+				}
+				if n == 1 {
+					next = pageButton(t, response, "Next")
+				}
+			}
+		})
+	}
+}
+
+func TestPageArgsExpired(t *testing.T) {
+	echoPages(t)
+	pages, _ := pagers["echo"](nil, nil)
+	m := &recordingMessenger{}
+	RespondPages(m, commandInteraction("echo"), "echo", []string{strings.Repeat("a", 100)}, pages, 0)
+
+	next := pageButton(t, m.Responses[0], "Next")
+	unknown := next[:strings.LastIndex(next, storedArgsPrefix)+1] + "unknown"
+	i, data := pageInteraction(m.Responses[0], unknown)
+	HandlePageComponent(m, i, data)
+
+	response := m.Responses[1]
+	if len(response.Data.Components) != 0 {
+		t.Errorf("unknown key kept %d component rows", len(response.Data.Components))
+	}
+	if response.Data.Embeds[0].Description != pages[0].Description {
+		t.Errorf("expired page changed to %q", response.Data.Embeds[0].Description)
+	}
+}
+
+func TestRespondStoredPages(t *testing.T) {
+	pages := PageEmbeds(discordgo.MessageEmbed{Title: "Report"}, slices.Repeat([]string{strings.Repeat("x", 1000)}, 9), 4096)
+	if len(pages) != 3 {
+		t.Fatalf("got %d pages, want 3", len(pages))
+	}
+	m := &recordingMessenger{}
+	RespondStoredPages(m, commandInteraction("modpack"), pages, 0)
+
+	next := pageButton(t, m.Responses[0], "Next")
+	i, data := pageInteraction(m.Responses[0], next)
+	HandlePageComponent(m, i, data)
+	if page := m.Responses[1].Data.Embeds[0]; page.Description != pages[1].Description || page.Footer.Text != "Page 2/3" {
+		t.Errorf("got page %q %+v, want the second page", page.Description, page.Footer)
+	}
+
+	// Pages that are no longer stored behave like expired buttons.
+	key := next[strings.LastIndex(next, "|")+1:]
+	storedPages.mu.Lock()
+	delete(storedPages.entries, key)
+	storedPages.mu.Unlock()
+	HandlePageComponent(m, i, data)
+	if components := m.Responses[2].Data.Components; len(components) != 0 {
+		t.Errorf("expired stored pages kept %d component rows", len(components))
+	}
+
+	// A single page has no navigation and isn't stored.
+	m = &recordingMessenger{}
+	RespondStoredPages(m, commandInteraction("modpack"), pages[:1], 0)
+	if components := m.Responses[0].Data.Components; len(components) != 0 {
+		t.Errorf("single page has %d component rows", len(components))
+	}
+}
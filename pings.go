@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Mentions are the roles and users pinged alongside an update message.
+type Mentions struct {
+	Roles []string
+	Users []string
+}
+
+func (mentions *Mentions) Add(other Mentions) {
+	for _, role := range other.Roles {
+		if !slices.Contains(mentions.Roles, role) {
+			mentions.Roles = append(mentions.Roles, role)
+		}
+	}
+	for _, user := range other.Users {
+		if !slices.Contains(mentions.Users, user) {
+			mentions.Users = append(mentions.Users, user)
+		}
+	}
+}
+
+// Apply sets the message content to the mentions and allows exactly those
+// mentions to ping.
+func (mentions Mentions) Apply(data *discordgo.MessageSend) {
+	if len(mentions.Roles) == 0 && len(mentions.Users) == 0 {
+		return
+	}
+	var parts []string
+	for _, role := range mentions.Roles {
+		parts = append(parts, fmt.Sprintf("<@&%s>", role))
+	}
+	for _, user := range mentions.Users {
+		parts = append(parts, fmt.Sprintf("<@%s>", user))
+	}
+	data.Content = Truncate(strings.Join(parts, " "), 2000)
+	data.AllowedMentions = &discordgo.MessageAllowedMentions{
+		Roles: mentions.Roles,
+		Users: mentions.Users,
+	}
+}
+
+// Mentions returns the roles attached to a mod or its author and the members
+// who asked to be mentioned for the mod.
+func (guildData *GuildData) Mentions(modName, owner string) Mentions {
+	var mentions Mentions
+	mentions.Add(Mentions{Roles: guildData.ModPings[modName]})
+	mentions.Add(Mentions{Roles: guildData.AuthorPings[owner]})
+	mentions.Users = append(mentions.Users, SortedKeys(guildData.Subscribers[modName])...)
+	return mentions
+}
+
+// MentionOnce returns the mentions for a release unless they were already sent
+// to another of the guild's routes, recording the release in sent. Members and
+// roles are pinged once per release however many routes deliver it.
+func (guildData *GuildData) MentionOnce(sent map[string]bool, modName, owner, version string) Mentions {
+	key := modName + "@" + version
+	if sent[key] {
+		return Mentions{}
+	}
+	sent[key] = true
+	return guildData.Mentions(modName, owner)
+}
+
+// Tracks reports whether any of the guild's routes with a channel announces
+// the mod, which is where its subscribers are mentioned.
+func (guildData *GuildData) Tracks(mod Mod) bool {
+	if !guildData.TrackEnabled {
+		return false
+	}
+	for _, route := range guildData.AllRoutes() {
+		if route.Channel == "" {
+			continue
+		}
+		if route.TrackedMods[mod.Name] || route.TrackedAuthors[mod.Owner] {
+			return true
+		}
+		if route.TrackAll && route.AllowsCategories(mod) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddPing attaches a role to a key of pings, returning false if it was already
+// attached.
+func AddPing(pings map[string][]string, key, role string) bool {
+	if slices.Contains(pings[key], role) {
+		return false
+	}
+	pings[key] = append(pings[key], role)
+	return true
+}
+
+// RemovePing detaches a role from a key of pings, returning false if it wasn't
+// attached.
+func RemovePing(pings map[string][]string, key, role string) bool {
+	i := slices.Index(pings[key], role)
+	if i == -1 {
+		return false
+	}
+	pings[key] = slices.Delete(pings[key], i, i+1)
+	if len(pings[key]) == 0 {
+		delete(pings, key)
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// PortalClient fetches data from the Factorio mod portal.
+type PortalClient interface {
+	ListMods() (Response, error)
+	GetMod(name string) (FullMod, error)
+	GetFullMod(name string) (FullMod, error)
+	GetAuthorPage(name string) (string, error)
+}
+
+type HTTPPortalClient struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+func NewPortalClient(baseURL string) *HTTPPortalClient {
+	return &HTTPPortalClient{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Client:  &http.Client{Timeout: time.Minute},
+	}
+}
+
+func (client *HTTPPortalClient) get(path string) ([]byte, error) {
+	slog.Debug("Requesting mod portal", "path", path)
+	resp, err := client.Client.Get(client.BaseURL + path)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", path, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (client *HTTPPortalClient) getJson(path string, v any) error {
+	body, err := client.get(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+func (client *HTTPPortalClient) ListMods() (Response, error) {
+	var data Response
+	err := client.getJson("/api/mods?page_size=max", &data)
+	return data, err
+}
+
+func (client *HTTPPortalClient) GetMod(name string) (FullMod, error) {
+	var fullMod FullMod
+	err := client.getJson("/api/mods/"+url.PathEscape(name), &fullMod)
+	return fullMod, err
+}
+
+func (client *HTTPPortalClient) GetFullMod(name string) (FullMod, error) {
+	var fullMod FullMod
+	err := client.getJson("/api/mods/"+url.PathEscape(name)+"/full", &fullMod)
+	return fullMod, err
+}
+
+func (client *HTTPPortalClient) GetAuthorPage(name string) (string, error) {
+	body, err := client.get("/user/" + url.PathEscape(name))
+	return string(body), err
+}
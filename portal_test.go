@@ -0,0 +1,346 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// fakePortal serves mod portal responses from testdata. Individual paths can
+// be overridden to simulate new releases or failures.
+type fakePortal struct {
+	*httptest.Server
+	mu        sync.Mutex
+	overrides map[string]string
+	requests  map[string]int
+}
+
+func newFakePortal(t *testing.T) *fakePortal {
+	t.Helper()
+	fake := &fakePortal{overrides: map[string]string{}, requests: map[string]int{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/mods", fake.serveFile(func(r *http.Request) string {
+		return "mods.json"
+	}))
+	mux.HandleFunc("GET /api/mods/{name}", fake.serveFile(func(r *http.Request) string {
+		return filepath.Join("full", r.PathValue("name")+".json")
+	}))
+	mux.HandleFunc("GET /api/mods/{name}/full", fake.serveFile(func(r *http.Request) string {
+		return filepath.Join("full", r.PathValue("name")+".json")
+	}))
+	mux.HandleFunc("GET /user/{name}", fake.serveFile(func(r *http.Request) string {
+		return filepath.Join("user", r.PathValue("name")+".html")
+	}))
+	fake.Server = httptest.NewServer(mux)
+	t.Cleanup(fake.Close)
+	return fake
+}
+
+func (fake *fakePortal) serveFile(file func(r *http.Request) string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fake.mu.Lock()
+		fake.requests[r.URL.Path]++
+		body, overridden := fake.overrides[r.URL.Path]
+		fake.mu.Unlock()
+		if overridden {
+			w.Write([]byte(body))
+			return
+		}
+		data, err := os.ReadFile(filepath.Join("testdata", file(r)))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}
+}
+
+// Override serves body for path instead of the testdata file.
+func (fake *fakePortal) Override(path, body string) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	fake.overrides[path] = body
+}
+
+// Requests returns how often path has been requested.
+func (fake *fakePortal) Requests(path string) int {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return fake.requests[path]
+}
+
+// setupTest points the global configuration, portal client and stores at a
+// fake portal and a temporary data directory.
+func setupTest(t *testing.T) *fakePortal {
+	t.Helper()
+	fake := newFakePortal(t)
+
+	config = DefaultConfig()
+	config.Token = "test"
+	config.DataDir = t.TempDir()
+	config.PortalURL = fake.URL
+	config.AssetsURL = fake.URL
+	portal = NewPortalClient(fake.URL)
+	modCache = NewFullModCache(config.CacheSize, config.CacheTTL, "")
+
+	var err error
+	guildStore, userStore, err = OpenStores(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	releaseState, err = LoadReleaseState(config.ReleaseStatePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dependencyIndex, err = LoadDependencyIndex(config.DependencyIndexPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	CacheModList(nil)
+	return fake
+}
+
+func TestPortalClient(t *testing.T) {
+	setupTest(t)
+
+	list, err := portal.ListMods()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Results) != 3 {
+		t.Fatalf("got %d mods, want 3", len(list.Results))
+	}
+
+	fullMod, err := portal.GetFullMod("example-mod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fullMod.Releases) != 3 || fullMod.Title != "Example Mod" {
+		t.Errorf("unexpected full mod %+v", fullMod)
+	}
+
+	if _, err := portal.GetFullMod("no-such-mod"); err == nil {
+		t.Error("expected an error for a missing mod")
+	}
+}
+
+func TestAuthorThumbnail(t *testing.T) {
+	fake := setupTest(t)
+	fake.Override("/user/no-marker", "<html><body>Maintenance</body></html>")
+	fake.Override("/user/no-src", `<img class="author-card-thumbnail" alt="x">`)
+	fake.Override("/user/unterminated", `<img class="author-card-thumbnail" src="https://assets.example.com/avatars/x.png`)
+
+	tests := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{"alice", "https://assets.example.com/avatars/alice.png", true},
+		{"bob", "", true},
+		{"no-marker", "", false},
+		{"no-src", "", false},
+		{"unterminated", "", false},
+		{"missing", "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := (Author{Name: test.name}).Thumbnail()
+			if (err == nil) != test.ok {
+				t.Fatalf("Thumbnail() error = %v, want ok %v", err, test.ok)
+			}
+			if got != test.want {
+				t.Errorf("Thumbnail() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCacheModList(t *testing.T) {
+	setupTest(t)
+
+	list, err := portal.ListMods()
+	if err != nil {
+		t.Fatal(err)
+	}
+	CacheModList(list.Results)
+
+	if mods["example-mod"] == nil || mods["example-mod"].Title != "Example Mod" {
+		t.Errorf("example-mod not cached: %+v", mods["example-mod"])
+	}
+	alice := authors["alice"]
+	if alice == nil || len(alice.Mods) != 2 || alice.Downloads != 1500 {
+		t.Errorf("unexpected author alice: %+v", alice)
+	}
+	if len(allAuthors) != 2 || allAuthors[0].Name != "alice" {
+		t.Errorf("authors not sorted by downloads: %v", allAuthors)
+	}
+
+	// Internal mods sort after the rest regardless of downloads.
+	var names []string
+	for _, mod := range versions["2.0"] {
+		names = append(names, mod.Name)
+	}
+	if len(names) != 2 || names[0] != "example-mod" || names[1] != "example-lib" {
+		t.Errorf("versions[2.0] = %v", names)
+	}
+	if len(versions["all"]) != 3 || len(versions["1.1"]) != 1 {
+		t.Errorf("unexpected version index: all=%d 1.1=%d", len(versions["all"]), len(versions["1.1"]))
+	}
+	if categories["content"] != 1 || categories["logistics"] != 1 || categories["internal"] != 1 {
+		t.Errorf("unexpected categories %v", categories)
+	}
+}
+
+func TestUpdateMods(t *testing.T) {
+	fake := setupTest(t)
+	m := &recordingMessenger{}
+
+	// The first run only seeds the release state.
+	UpdateMods(m)
+	if len(m.Messages) != 0 {
+		t.Fatalf("seeding sent %d messages", len(m.Messages))
+	}
+	if got := releaseState.Versions["example-mod"]; got != "1.2.0" {
+		t.Fatalf("seeded version = %q, want 1.2.0", got)
+	}
+
+	err := guildStore.Update("guild", func(guildData *GuildData) error {
+		guildData.TrackEnabled = true
+		guildData.Channel = "updates"
+		guildData.TrackedMods["example-mod"] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Pretend only 1.0.0 was announced, so 1.1.0 and 1.2.0 are pending.
+	releaseState.Versions["example-mod"] = "1.0.0"
+	UpdateMods(m)
+
+	if len(m.Messages) != 2 {
+		t.Fatalf("sent %d messages, want 2", len(m.Messages))
+	}
+	for n, version := range []string{"1.1.0", "1.2.0"} {
+		message := m.Messages[n]
+		if message.ChannelID != "updates" {
+			t.Errorf("message %d sent to %q", n, message.ChannelID)
+		}
+		embed := message.Data.Embeds[0]
+		if embed.Title != "Example Mod" || embed.Fields[1].Value != version {
+			t.Errorf("message %d: title %q version %q, want Example Mod %s", n, embed.Title, embed.Fields[1].Value, version)
+		}
+	}
+
+	state, err := LoadReleaseState(config.ReleaseStatePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := state.Versions["example-mod"]; got != "1.2.0" {
+		t.Errorf("saved version = %q, want 1.2.0", got)
+	}
+	if len(m.Statuses) != 2 {
+		t.Errorf("status set %d times, want 2", len(m.Statuses))
+	}
+	if got := fake.Requests("/api/mods/example-mod/full"); got != 1 {
+		t.Errorf("example-mod requested %d times, want 1", got)
+	}
+
+	// Nothing new on the next poll.
+	UpdateMods(m)
+	if len(m.Messages) != 2 {
+		t.Errorf("repeat poll sent %d more messages", len(m.Messages)-2)
+	}
+}
+
+func TestUpdateModsFullLagsList(t *testing.T) {
+	fake := setupTest(t)
+	m := &recordingMessenger{}
+	UpdateMods(m)
+
+	err := guildStore.Update("guild", func(guildData *GuildData) error {
+		guildData.TrackEnabled = true
+		guildData.Channel = "updates"
+		guildData.TrackedMods["example-mod"] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The mod list already shows 1.2.0, but the full details don't include it.
+	original, err := os.ReadFile(filepath.Join("testdata", "full", "example-mod.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lagging map[string]any
+	if err := json.Unmarshal(original, &lagging); err != nil {
+		t.Fatal(err)
+	}
+	releases := lagging["releases"].([]any)
+	lagging["releases"] = releases[:len(releases)-1]
+	body, err := json.Marshal(lagging)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.Override("/api/mods/example-mod/full", string(body))
+
+	releaseState.Versions["example-mod"] = "1.1.0"
+	UpdateMods(m)
+	if len(m.Messages) != 0 {
+		t.Fatalf("sent %d messages for releases missing from the details", len(m.Messages))
+	}
+	if got := releaseState.Versions["example-mod"]; got != "1.1.0" {
+		t.Fatalf("version advanced to %q before 1.2.0 was announced", got)
+	}
+
+	fake.Override("/api/mods/example-mod/full", string(original))
+	UpdateMods(m)
+	if len(m.Messages) != 1 || m.Messages[0].Data.Embeds[0].Fields[1].Value != "1.2.0" {
+		t.Fatalf("sent %d messages once the details caught up, want 1.2.0", len(m.Messages))
+	}
+	if got := releaseState.Versions["example-mod"]; got != "1.2.0" {
+		t.Errorf("version = %q, want 1.2.0", got)
+	}
+}
+
+func TestUpdateModsMentionsOnce(t *testing.T) {
+	setupTest(t)
+	m := &recordingMessenger{}
+	UpdateMods(m)
+
+	err := guildStore.Update("guild", func(guildData *GuildData) error {
+		guildData.TrackEnabled = true
+		guildData.Channel = "updates"
+		guildData.TrackedMods["example-mod"] = true
+		route := guildData.CreateRoute("alice")
+		route.Channel = "alice-mods"
+		route.TrackedAuthors["alice"] = true
+		route.TrackedMods["example-mod"] = true
+		guildData.Subscribers["example-mod"] = map[string]bool{"member": true}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	releaseState.Versions["example-mod"] = "1.1.0"
+	UpdateMods(m)
+
+	if len(m.Messages) != 2 {
+		t.Fatalf("sent %d messages, want one per route", len(m.Messages))
+	}
+	var mentioned int
+	for _, message := range m.Messages {
+		if message.Data.Content == "<@member>" {
+			mentioned++
+		}
+	}
+	if mentioned != 1 {
+		t.Errorf("member mentioned %d times, want 1", mentioned)
+	}
+}
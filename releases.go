@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// ReleaseState records the last announced version of every mod so that each
+// release is announced exactly once, even across restarts and failed requests.
+type ReleaseState struct {
+	filename string
+	seeded   bool
+	Versions map[string]string
+}
+
+func LoadReleaseState(filename string) (*ReleaseState, error) {
+	state := &ReleaseState{filename: filename, Versions: map[string]string{}}
+	err := ReadJson(filename, &state.Versions)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	state.seeded = true
+	return state, nil
+}
+
+// Seed marks the latest release of every mod as announced. It is used on the
+// first run so the bot doesn't announce every mod on the portal.
+func (state *ReleaseState) Seed(modList []Mod) {
+	for _, mod := range modList {
+		state.Versions[mod.Name] = mod.LatestRelease.Version
+	}
+	state.seeded = true
+}
+
+func (state *ReleaseState) Save() error {
+	return WriteJson(state.filename, state.Versions)
+}
+
+// Pending returns the releases of mod that have not been announced yet, oldest
+// first. Mods that have never been seen only report their latest release.
+func (state *ReleaseState) Pending(mod FullMod) []Release {
+	last, ok := state.Versions[mod.Name]
+	if ok {
+		var releases []Release
+		for _, release := range mod.Releases {
+			if CompareVersions(release.Version, last) > 0 {
+				releases = append(releases, release)
+			}
+		}
+		return releases
+	}
+	if len(mod.Releases) == 0 {
+		return nil
+	}
+	return mod.Releases[len(mod.Releases)-1:]
+}
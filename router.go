@@ -0,0 +1,132 @@
+package main
+
+import (
+	"expvar"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// interactionPanics counts handler panics recovered by the router.
+var interactionPanics = expvar.NewInt("interaction_panics")
+
+// MetricsHandler serves the expvar metrics, including interactionPanics.
+func MetricsHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /debug/vars", expvar.Handler())
+	return mux
+}
+
+type ComponentHandler func(m Messenger, i *discordgo.InteractionCreate, data discordgo.MessageComponentInteractionData)
+
+type ModalHandler func(m Messenger, i *discordgo.InteractionCreate, data discordgo.ModalSubmitInteractionData)
+
+// Router dispatches interactions by type. Commands and their autocomplete are
+// routed by command name; components and modals by the prefix of their
+// custom ID, which is everything before the first "|".
+type Router struct {
+	Commands   map[string]CommandHandler
+	Components map[string]ComponentHandler
+	Modals     map[string]ModalHandler
+}
+
+func NewRouter() *Router {
+	return &Router{
+		Commands:   map[string]CommandHandler{},
+		Components: map[string]ComponentHandler{},
+		Modals:     map[string]ModalHandler{},
+	}
+}
+
+func (router *Router) HandleCommand(name string, handler CommandHandler) {
+	router.Commands[name] = handler
+}
+
+func (router *Router) HandleComponent(prefix string, handler ComponentHandler) {
+	router.Components[prefix] = handler
+}
+
+func (router *Router) HandleModal(prefix string, handler ModalHandler) {
+	router.Modals[prefix] = handler
+}
+
+// CustomIDPrefix returns the routing prefix of a custom ID.
+func CustomIDPrefix(customID string) string {
+	prefix, _, _ := strings.Cut(customID, "|")
+	return prefix
+}
+
+func (router *Router) Dispatch(m Messenger, i *discordgo.InteractionCreate) {
+	defer Recover(m, i)
+	slog.Debug("Dispatching interaction", "interaction", InteractionName(i), "guild", i.GuildID, "user", InteractionUserID(i))
+
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand, discordgo.InteractionApplicationCommandAutocomplete:
+		data := i.ApplicationCommandData()
+		if handler, ok := router.Commands[data.Name]; ok {
+			handler(m, i, data)
+			return
+		}
+		slog.Warn("Unhandled command", "command", data.Name)
+	case discordgo.InteractionMessageComponent:
+		data := i.MessageComponentData()
+		if handler, ok := router.Components[CustomIDPrefix(data.CustomID)]; ok {
+			handler(m, i, data)
+			return
+		}
+		slog.Warn("Unhandled component", "custom_id", data.CustomID)
+	case discordgo.InteractionModalSubmit:
+		data := i.ModalSubmitData()
+		if handler, ok := router.Modals[CustomIDPrefix(data.CustomID)]; ok {
+			handler(m, i, data)
+			return
+		}
+		slog.Warn("Unhandled modal", "custom_id", data.CustomID)
+	default:
+		slog.Warn("Unhandled interaction type", "type", i.Type)
+	}
+}
+
+// Recover handles a panic in an interaction handler by logging it with the
+// interaction's context and replying with an error instead of crashing.
+func Recover(m Messenger, i *discordgo.InteractionCreate) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	interactionPanics.Add(1)
+	slog.Error("Panic handling interaction", "interaction", InteractionName(i), "guild", i.GuildID,
+		"user", InteractionUserID(i), "total", interactionPanics.Value(), "panic", r, "stack", string(debug.Stack()))
+
+	if i.Type == discordgo.InteractionApplicationCommandAutocomplete {
+		RespondChoices(m, i, nil)
+	} else {
+		RespondDefaultError(m, i)
+	}
+}
+
+// InteractionName describes an interaction for logging, e.g. "command /track"
+// or "component page|...".
+func InteractionName(i *discordgo.InteractionCreate) string {
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand, discordgo.InteractionApplicationCommandAutocomplete:
+		data := i.ApplicationCommandData()
+		name := "/" + data.Name
+		for options := data.Options; len(options) > 0; options = options[0].Options {
+			if options[0].Type != discordgo.ApplicationCommandOptionSubCommandGroup && options[0].Type != discordgo.ApplicationCommandOptionSubCommand {
+				break
+			}
+			name += " " + options[0].Name
+		}
+		return fmt.Sprintf("%s %s", Ternary(i.Type == discordgo.InteractionApplicationCommand, "command", "autocomplete"), name)
+	case discordgo.InteractionMessageComponent:
+		return "component " + i.MessageComponentData().CustomID
+	case discordgo.InteractionModalSubmit:
+		return "modal " + i.ModalSubmitData().CustomID
+	}
+	return i.Type.String()
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestDispatchRecoversPanic(t *testing.T) {
+	router := NewRouter()
+	router.HandleCommand("panic", func(m Messenger, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+		panic("handler failed")
+	})
+
+	before := interactionPanics.Value()
+	m := &recordingMessenger{}
+	router.Dispatch(m, commandInteraction("panic"))
+
+	if got := interactionPanics.Value() - before; got != 1 {
+		t.Errorf("interaction_panics increased by %d, want 1", got)
+	}
+	if embed := respondedEmbed(t, m); embed.Title != "ERROR: Process Failed" {
+		t.Errorf("got title %q, want the default error", embed.Title)
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	interactionPanics.Add(1)
+
+	rec := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+	}
+	var vars map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &vars); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := vars["interaction_panics"], float64(interactionPanics.Value()); got != want {
+		t.Errorf("got interaction_panics %v, want %v", got, want)
+	}
+
+	rec = httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("got status %d for /, want %d", rec.Code, http.StatusNotFound)
+	}
+}
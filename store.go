@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Store persists one value per ID. Update runs fn as a single transaction:
+// concurrent updates to the same store are serialized and the result of fn is
+// only written if it returns nil.
+type Store[T any] interface {
+	Get(id string) (T, error)
+	Update(id string, fn func(value *T) error) error
+	Delete(id string) error
+	All() (map[string]T, error)
+}
+
+type GuildStore = Store[GuildData]
+type UserStore = Store[UserData]
+
+// record is implemented by stored types, whose init fills in fields missing
+// from older or freshly created entries.
+type record[T any] interface {
+	*T
+	init()
+}
+
+// OpenStores opens the guild and user stores, either as a single bbolt
+// database or as one JSON file each.
+func OpenStores(config Config) (GuildStore, UserStore, error) {
+	if config.GuildStore == "bolt" {
+		db, err := bolt.Open(config.GuildStorePath(), 0644, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		guilds, err := NewBoltStore[GuildData](db, "guilds")
+		if err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+		users, err := NewBoltStore[UserData](db, "users")
+		if err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+		return guilds, users, nil
+	}
+	return NewJsonStore[GuildData](config.GuildStorePath()), NewJsonStore[UserData](config.UserStorePath()), nil
+}
+
+type JsonStore[T any, P record[T]] struct {
+	mu       sync.Mutex
+	filename string
+}
+
+func NewJsonStore[T any, P record[T]](filename string) *JsonStore[T, P] {
+	return &JsonStore[T, P]{filename: filename}
+}
+
+func (store *JsonStore[T, P]) load() (map[string]T, error) {
+	valueMap := map[string]T{}
+	err := ReadJson(store.filename, &valueMap)
+	if errors.Is(err, os.ErrNotExist) {
+		return valueMap, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for id, value := range valueMap {
+		P(&value).init()
+		valueMap[id] = value
+	}
+	return valueMap, nil
+}
+
+func (store *JsonStore[T, P]) Get(id string) (T, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	var value T
+	valueMap, err := store.load()
+	if err != nil {
+		return value, err
+	}
+	value, ok := valueMap[id]
+	if !ok {
+		P(&value).init()
+	}
+	return value, nil
+}
+
+func (store *JsonStore[T, P]) Update(id string, fn func(value *T) error) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	valueMap, err := store.load()
+	if err != nil {
+		return err
+	}
+	value, ok := valueMap[id]
+	if !ok {
+		P(&value).init()
+	}
+	if err := fn(&value); err != nil {
+		return err
+	}
+	valueMap[id] = value
+	return WriteJson(store.filename, valueMap)
+}
+
+func (store *JsonStore[T, P]) Delete(id string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	valueMap, err := store.load()
+	if err != nil {
+		return err
+	}
+	delete(valueMap, id)
+	return WriteJson(store.filename, valueMap)
+}
+
+func (store *JsonStore[T, P]) All() (map[string]T, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	return store.load()
+}
+
+type BoltStore[T any, P record[T]] struct {
+	db     *bolt.DB
+	bucket []byte
+}
+
+func NewBoltStore[T any, P record[T]](db *bolt.DB, bucket string) (*BoltStore[T, P], error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &BoltStore[T, P]{db: db, bucket: []byte(bucket)}, nil
+}
+
+func (store *BoltStore[T, P]) decode(data []byte) (T, error) {
+	var value T
+	if data != nil {
+		if err := json.Unmarshal(data, &value); err != nil {
+			return value, err
+		}
+	}
+	P(&value).init()
+	return value, nil
+}
+
+func (store *BoltStore[T, P]) Get(id string) (value T, err error) {
+	err = store.db.View(func(tx *bolt.Tx) error {
+		value, err = store.decode(tx.Bucket(store.bucket).Get([]byte(id)))
+		return err
+	})
+	return value, err
+}
+
+func (store *BoltStore[T, P]) Update(id string, fn func(value *T) error) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(store.bucket)
+		value, err := store.decode(bucket.Get([]byte(id)))
+		if err != nil {
+			return err
+		}
+		if err := fn(&value); err != nil {
+			return err
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(id), data)
+	})
+}
+
+func (store *BoltStore[T, P]) Delete(id string) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(store.bucket).Delete([]byte(id))
+	})
+}
+
+func (store *BoltStore[T, P]) All() (map[string]T, error) {
+	valueMap := map[string]T{}
+	err := store.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(store.bucket).ForEach(func(key, data []byte) error {
+			value, err := store.decode(data)
+			if err != nil {
+				return err
+			}
+			valueMap[string(key)] = value
+			return nil
+		})
+	})
+	return valueMap, err
+}
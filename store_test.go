@@ -0,0 +1,228 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// legacyGuild is a guild as written by the bot before routes and stores.
+const legacyGuild = `{"channel": "updates", "changelogs": true, "track_enabled": true, "track_all": false, "tracked_mods": {"example-mod": true}, "tracked_authors": null}`
+
+// testStores runs test against stores of every backend in a fresh data
+// directory.
+func testStores(t *testing.T, test func(t *testing.T, config Config)) {
+	for _, backend := range []string{"json", "bolt"} {
+		t.Run(backend, func(t *testing.T) {
+			config := DefaultConfig()
+			config.DataDir = t.TempDir()
+			config.GuildStore = backend
+			test(t, config)
+		})
+	}
+}
+
+func openStores(t *testing.T, config Config) (GuildStore, UserStore) {
+	t.Helper()
+	guilds, users, err := OpenStores(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { closeStores(guilds) })
+	return guilds, users
+}
+
+// closeStores releases the bolt database so it can be opened again.
+func closeStores(guilds GuildStore) {
+	if store, ok := guilds.(*BoltStore[GuildData, *GuildData]); ok {
+		store.db.Close()
+	}
+}
+
+func TestStoreUpdate(t *testing.T) {
+	testStores(t, func(t *testing.T, config Config) {
+		guilds, _ := openStores(t, config)
+
+		// Missing entries are initialized.
+		guildData, err := guilds.Get("guild")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if guildData.TrackedMods == nil || guildData.Routes == nil {
+			t.Errorf("missing guild not initialized: %+v", guildData)
+		}
+
+		err = guilds.Update("guild", func(guildData *GuildData) error {
+			guildData.Channel = "updates"
+			guildData.TrackedMods["a"] = true
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = guilds.Update("guild", func(guildData *GuildData) error {
+			guildData.TrackedMods["b"] = true
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// A failed update is not written.
+		failed := errors.New("failed")
+		err = guilds.Update("guild", func(guildData *GuildData) error {
+			guildData.Channel = "other"
+			return failed
+		})
+		if !errors.Is(err, failed) {
+			t.Errorf("update error = %v, want %v", err, failed)
+		}
+
+		guildData, err = guilds.Get("guild")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if guildData.Channel != "updates" || !guildData.TrackedMods["a"] || !guildData.TrackedMods["b"] {
+			t.Errorf("got %+v after updates", guildData.Route)
+		}
+	})
+}
+
+func TestStoreDeleteAndAll(t *testing.T) {
+	testStores(t, func(t *testing.T, config Config) {
+		guilds, _ := openStores(t, config)
+		for _, id := range []string{"a", "b", "c"} {
+			err := guilds.Update(id, func(guildData *GuildData) error {
+				guildData.Channel = id
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := guilds.Delete("b"); err != nil {
+			t.Fatal(err)
+		}
+		// Deleting a missing entry is not an error.
+		if err := guilds.Delete("missing"); err != nil {
+			t.Fatal(err)
+		}
+
+		guildMap, err := guilds.All()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(guildMap) != 2 || guildMap["a"].Channel != "a" || guildMap["c"].Channel != "c" {
+			t.Errorf("All() = %v, want a and c", guildMap)
+		}
+		if guildMap["a"].TrackedMods == nil {
+			t.Error("All() returned an uninitialized guild")
+		}
+
+		guildData, err := guilds.Get("b")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if guildData.Channel != "" {
+			t.Errorf("deleted guild still has channel %q", guildData.Channel)
+		}
+	})
+}
+
+func TestStoreReopen(t *testing.T) {
+	testStores(t, func(t *testing.T, config Config) {
+		guilds, users, err := OpenStores(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = guilds.Update("guild", func(guildData *GuildData) error {
+			guildData.TrackedMods["a"] = true
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = users.Update("user", func(userData *UserData) error {
+			userData.Mods["a"] = true
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		closeStores(guilds)
+
+		guilds, users = openStores(t, config)
+		guildData, err := guilds.Get("guild")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !guildData.TrackedMods["a"] {
+			t.Errorf("guild not persisted: %+v", guildData.Route)
+		}
+		userData, err := users.Get("user")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !userData.Mods["a"] {
+			t.Errorf("user not persisted: %+v", userData)
+		}
+	})
+}
+
+func TestStoreLegacyGuilds(t *testing.T) {
+	testStores(t, func(t *testing.T, config Config) {
+		if config.GuildStore == "bolt" {
+			db, err := bolt.Open(config.GuildStorePath(), 0644, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = db.Update(func(tx *bolt.Tx) error {
+				bucket, err := tx.CreateBucket([]byte("guilds"))
+				if err != nil {
+					return err
+				}
+				return bucket.Put([]byte("guild"), []byte(legacyGuild))
+			})
+			db.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+		} else {
+			err := os.WriteFile(filepath.Join(config.DataDir, "guilds.json"), []byte(`{"guild": `+legacyGuild+`}`), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		guilds, _ := openStores(t, config)
+		guildData, err := guilds.Get("guild")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if guildData.Channel != "updates" || !guildData.Changelogs || !guildData.TrackEnabled || !guildData.TrackedMods["example-mod"] {
+			t.Errorf("legacy guild decoded as %+v", guildData.Route)
+		}
+		if guildData.TrackedAuthors == nil || guildData.Routes == nil || guildData.Subscribers == nil {
+			t.Errorf("legacy guild not initialized: %+v", guildData)
+		}
+
+		// Legacy guilds can be updated in place.
+		err = guilds.Update("guild", func(guildData *GuildData) error {
+			guildData.TrackedAuthors["alice"] = true
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		guildMap, err := guilds.All()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if guildData := guildMap["guild"]; !guildData.TrackedAuthors["alice"] || !guildData.TrackedMods["example-mod"] {
+			t.Errorf("updated legacy guild = %+v", guildData.Route)
+		}
+	})
+}
@@ -2,8 +2,7 @@ package main
 
 import (
 	"fmt"
-	"log"
-	"os"
+	"log/slog"
 	"slices"
 	"time"
 
@@ -16,47 +15,50 @@ var (
 	authors    map[string]*Author
 	allAuthors []*Author
 	versions   map[string][]*Mod
+	categories map[string]int
 )
 
 type SpecificRelease struct {
 	Mod     FullMod
 	Release Release
+	IsNew   bool
 }
 
-func UpdateMods() {
-	file, err := os.ReadFile("time.txt")
-	if err != nil {
-		log.Fatal(err)
-	}
-	lastUpdated := string(file)
-
+func UpdateMods(m Messenger) {
 	now := time.Now().UTC()
-	defer s.UpdateCustomStatus(fmt.Sprintf("Updated: %d/%02d %d:%02d", int(now.Month()), now.Day(), now.Hour(), now.Minute()))
+	defer m.SetStatus(fmt.Sprintf("Updated: %d/%02d %d:%02d", int(now.Month()), now.Day(), now.Hour(), now.Minute()))
 
-	modList, err := BulkRequest("https://mods.factorio.com/api/mods?page_size=max")
+	modList, err := portal.ListMods()
 	if err != nil {
-		log.Printf("Could not request mods: %v", err)
+		slog.Error("Could not request mods", "err", err)
 		return
 	}
 
-	go CacheModList(modList.Results)
+	// Drop stale full mods before requesting the updated ones below.
+	modCache.Refresh(modList.Results)
+	CacheModList(modList.Results)
+
+	if !releaseState.seeded {
+		releaseState.Seed(modList.Results)
+		if err := releaseState.Save(); err != nil {
+			slog.Error("Could not save release state", "err", err)
+		}
+		slog.Info("Seeded release state", "mods", len(modList.Results))
+		return
+	}
 
 	var updated []Mod
-	newLastUpdated := lastUpdated
 	for _, mod := range modList.Results {
 		if mod.FactorioVersion() == "" {
 			continue
 		}
-		if mod.LatestRelease.ReleasedAt <= lastUpdated {
+		if CompareVersions(releaseState.Versions[mod.Name], mod.LatestRelease.Version) >= 0 {
 			continue
 		}
 		updated = append(updated, mod)
-		if mod.LatestRelease.ReleasedAt > newLastUpdated {
-			newLastUpdated = mod.LatestRelease.ReleasedAt
-		}
 	}
 
-	defer log.Printf("Updated %d mods\n", len(updated))
+	defer slog.Info("Updated mods", "mods", len(updated))
 
 	if len(updated) == 0 {
 		return
@@ -65,20 +67,18 @@ func UpdateMods() {
 	var fullMods []FullMod
 	for _, mod := range updated {
 		fullMod, err := mod.Request(true)
-		if err == nil {
-			fullMods = append(fullMods, fullMod)
+		if err != nil {
+			slog.Error("Could not request mod", "mod", mod.Name, "err", err)
+			continue
 		}
+		fullMods = append(fullMods, fullMod)
 	}
 
 	var releases []SpecificRelease
 	for _, fullMod := range fullMods {
-		for i := len(fullMod.Releases) - 1; i >= 0; i-- {
-			release := fullMod.Releases[i]
-			if release.ReleasedAt < lastUpdated {
-				break
-			}
-			// fullMod.LatestRelease = release
-			releases = append(releases, SpecificRelease{Mod: fullMod, Release: release})
+		_, known := releaseState.Versions[fullMod.Name]
+		for i, release := range releaseState.Pending(fullMod) {
+			releases = append(releases, SpecificRelease{Mod: fullMod, Release: release, IsNew: !known && i == 0})
 		}
 	}
 
@@ -86,38 +86,90 @@ func UpdateMods() {
 		return Ternary(a.Release.ReleasedAt <= b.Release.ReleasedAt, -1, 1)
 	})
 
-	var guildMap map[string]GuildData
-	ReadJson("guilds.json", &guildMap)
-	for _, guildData := range guildMap {
-		if !guildData.TrackEnabled || guildData.Channel == "" {
-			continue
-		}
-		alreadyNew := map[string]bool{}
-		for _, release := range releases {
-			mod := release.Mod
-			isNew := mod.CreatedAt > lastUpdated && !alreadyNew[mod.Name]
-			if isNew {
-				alreadyNew[mod.Name] = true
+	guildMap, err := guildStore.All()
+	if err != nil {
+		slog.Error("Could not read guilds", "err", err)
+		return
+	}
+	for guildID := range guildMap {
+		var guildData GuildData
+		routes := map[string]*Route{}
+		toSend := map[string][]SpecificRelease{}
+		err := guildStore.Update(guildID, func(g *GuildData) error {
+			clear(routes)
+			clear(toSend)
+			guildData = *g
+			if !g.TrackEnabled {
+				return nil
 			}
-			if !guildData.TrackAll {
-				if isNew && guildData.TrackedAuthors[mod.Owner] {
-					guildData.TrackedMods[mod.Name] = true
-				} else if !guildData.TrackedMods[mod.Name] {
+			for name, route := range g.AllRoutes() {
+				if route.Channel == "" {
 					continue
 				}
+				for _, release := range releases {
+					mod := release.Mod
+					if !route.AllowsVersion(release.Release.FactorioVersion()) {
+						continue
+					}
+					if route.TrackAll {
+						if !route.AllowsCategories(*mod.Mod) {
+							continue
+						}
+					} else {
+						if release.IsNew && route.TrackedAuthors[mod.Owner] {
+							route.TrackedMods[mod.Name] = true
+						} else if !route.TrackedMods[mod.Name] {
+							continue
+						}
+					}
+					if g.Delivery == DeliveryDigest {
+						g.Pending = append(g.Pending, NewPendingRelease(name, release))
+					} else {
+						toSend[name] = append(toSend[name], release)
+					}
+				}
+				copied := *route
+				routes[name] = &copied
 			}
+			return nil
+		})
+		if err != nil {
+			slog.Error("Could not update guild", "guild", guildID, "err", err)
+			continue
+		}
 
-			UpdateMessageSend(guildData, mod, release.Release.Version, isNew)
+		mentioned := map[string]bool{}
+		for _, name := range SortedKeys(toSend) {
+			DeliverReleases(m, guildID, name, routes[name], guildData, toSend[name], mentioned)
 		}
 	}
-	WriteJson("guilds.json", &guildMap)
 
-	go func() {
-		for os.WriteFile("time.txt", []byte(newLastUpdated), 0644) != nil {}
-	}()
+	SendDMs(m, releases)
+
+	// Only releases the full details include have been announced. One the mod
+	// list shows before them stays pending for the next poll.
+	for _, fullMod := range fullMods {
+		release := fullMod.NewestRelease()
+		if release != nil && CompareVersions(release.Version, releaseState.Versions[fullMod.Name]) > 0 {
+			releaseState.Versions[fullMod.Name] = release.Version
+		}
+	}
+	if err := releaseState.Save(); err != nil {
+		slog.Error("Could not save release state", "err", err)
+	}
 }
 
-func UpdateMessageSend(guildData GuildData, mod FullMod, version string, isNew bool) {
+func UpdateMessageSend(m Messenger, guildID, routeName string, route *Route, mod FullMod, version string, isNew bool, mentions Mentions) {
+	embed := UpdateEmbed(*route, mod, version, isNew)
+	data := &discordgo.MessageSend{Embeds: []*discordgo.MessageEmbed{embed}}
+	mentions.Apply(data)
+	err := SendToRoute(m, guildID, routeName, route, data, mod.Title, mod.GetThumbnail())
+	if err != nil {
+		slog.Error("Could not send update", "guild", guildID, "route", routeName, "mod", mod.Name, "err", err)
+	}
+}
+
+func UpdateEmbed(route Route, mod FullMod, version string, isNew bool) *discordgo.MessageEmbed {
 	color := Ternary(isNew, colors.Green, colors.Blue)
 
 	embed := &discordgo.MessageEmbed{
@@ -126,7 +178,7 @@ func UpdateMessageSend(guildData GuildData, mod FullMod, version string, isNew b
 		Color: color,
 		Fields: []*discordgo.MessageEmbedField{{
 			Name:   "Author:",
-			Value:  fmt.Sprintf("[%s](https://mods.factorio.com/user/%s)", mod.Owner, mod.Owner),
+			Value:  fmt.Sprintf("[%s](%s)", mod.Owner, UserURL(mod.Owner)),
 			Inline: true,
 		}, {
 			Name:   "Version:",
@@ -134,16 +186,16 @@ func UpdateMessageSend(guildData GuildData, mod FullMod, version string, isNew b
 			Inline: true,
 		}},
 	}
-	if mod.Thumbnail != "" && mod.Thumbnail != "/assets/.thumb.png" {
-		embed.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: "https://assets-mod.factorio.com" + mod.Thumbnail}
+	if thumbnail := mod.GetThumbnail(); thumbnail != "" {
+		embed.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: thumbnail}
 	}
-	if guildData.Changelogs {
+	if route.Changelogs {
 		changelog := mod.FormatChangelog(version)
 		if changelog != "" {
 			embed.Description = changelog
 			embed.Fields = []*discordgo.MessageEmbedField{{
 				Name:   "",
-				Value:  fmt.Sprintf("**Author:** [%s](https://mods.factorio.com/user/%s)", mod.Owner, mod.Owner),
+				Value:  fmt.Sprintf("**Author:** [%s](%s)", mod.Owner, UserURL(mod.Owner)),
 				Inline: true,
 			}, {
 				Name:   "",
@@ -153,10 +205,7 @@ func UpdateMessageSend(guildData GuildData, mod FullMod, version string, isNew b
 		}
 	}
 
-	_, err := s.ChannelMessageSendEmbed(guildData.Channel, embed)
-	if err != nil {
-		log.Println(err)
-	}
+	return embed
 }
 
 func CacheModList(modList []Mod) {
@@ -172,6 +221,7 @@ func CacheModList(modList []Mod) {
 	newAuthors := map[string]*Author{}
 	var newAllAuthors []*Author
 	newVersions := map[string][]*Mod{}
+	newCategories := map[string]int{}
 
 	for _, mod := range modList {
 		newMods[mod.Name] = &mod
@@ -187,6 +237,9 @@ func CacheModList(modList []Mod) {
 			newVersions[version] = append(newVersions[version], &mod)
 		}
 		newVersions["all"] = append(newVersions["all"], &mod)
+		for _, label := range mod.Categories() {
+			newCategories[label]++
+		}
 	}
 
 	slices.SortFunc(newAllAuthors, func(a, b *Author) int {
@@ -208,4 +261,5 @@ func CacheModList(modList []Mod) {
 	authors = newAuthors
 	allAuthors = newAllAuthors
 	versions = newVersions
+	categories = newCategories
 }
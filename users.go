@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// UserData holds a user's personal subscriptions, which are delivered by
+// direct message regardless of which guilds the user is in.
+type UserData struct {
+	Mods    map[string]bool `json:"mods"`
+	Authors map[string]bool `json:"authors"`
+}
+
+func (userData *UserData) init() {
+	if userData.Mods == nil {
+		userData.Mods = map[string]bool{}
+	}
+	if userData.Authors == nil {
+		userData.Authors = map[string]bool{}
+	}
+}
+
+func (userData UserData) Subscribed(mod Mod) bool {
+	return userData.Mods[mod.Name] || userData.Authors[mod.Owner]
+}
+
+func InteractionUserID(i *discordgo.InteractionCreate) string {
+	if i.Member != nil {
+		return i.Member.User.ID
+	}
+	return i.User.ID
+}
+
+// IsDMClosed reports whether a direct message failed because the user can no
+// longer be messaged.
+func IsDMClosed(err error) bool {
+	var restErr *discordgo.RESTError
+	if !errors.As(err, &restErr) || restErr.Message == nil {
+		return false
+	}
+	switch restErr.Message.Code {
+	case discordgo.ErrCodeCannotSendMessagesToThisUser, discordgo.ErrCodeUnknownUser:
+		return true
+	}
+	return false
+}
+
+// MigrateLegacyDMs moves direct message subscriptions still stored on guilds
+// into the user store. Guilds are only rewritten without them once every user
+// has been updated, so an interrupted migration is retried on the next start.
+func MigrateLegacyDMs() error {
+	guildMap, err := guildStore.All()
+	if err != nil {
+		return err
+	}
+	var migrated []string
+	for guildID, guildData := range guildMap {
+		for modName, users := range guildData.LegacyDMs {
+			for _, userID := range users {
+				err := userStore.Update(userID, func(userData *UserData) error {
+					userData.Mods[modName] = true
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+		if len(guildData.LegacyDMs) > 0 {
+			migrated = append(migrated, guildID)
+		}
+	}
+	for _, guildID := range migrated {
+		err := guildStore.Update(guildID, func(guildData *GuildData) error {
+			guildData.LegacyDMs = nil
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		slog.Info("Migrated direct message subscriptions", "guild", guildID)
+	}
+	return nil
+}
+
+// SendDMs messages every subscriber about the releases they subscribed to.
+// Users whose direct messages are closed are unsubscribed from everything.
+func SendDMs(m Messenger, releases []SpecificRelease) {
+	if len(releases) == 0 {
+		return
+	}
+	userMap, err := userStore.All()
+	if err != nil {
+		slog.Error("Could not read users", "err", err)
+		return
+	}
+
+	for userID, userData := range userMap {
+		var embeds []*discordgo.MessageEmbed
+		for _, release := range releases {
+			if userData.Subscribed(*release.Mod.Mod) {
+				embeds = append(embeds, UpdateEmbed(Route{}, release.Mod, release.Release.Version, release.IsNew))
+			}
+		}
+		for _, chunk := range ChunkEmbeds(embeds) {
+			err := m.DirectMessage(userID, &discordgo.MessageSend{Embeds: chunk})
+			if err == nil {
+				continue
+			}
+			if IsDMClosed(err) {
+				slog.Warn("Direct messages are closed, removing subscriptions", "user", userID)
+				if err := userStore.Delete(userID); err != nil {
+					slog.Error("Could not delete user", "user", userID, "err", err)
+				}
+			} else {
+				slog.Error("Could not message user", "user", userID, "err", err)
+			}
+			break
+		}
+	}
+}
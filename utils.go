@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"slices"
 	"time"
 )
 
@@ -46,4 +47,13 @@ func Ternary[T any](a bool, b T, c T) T {
 func Timestamp(t string) string {
 	timestamp, _ := time.Parse(time.RFC3339Nano, t)
 	return fmt.Sprintf("<t:%d:R>", timestamp.Unix())
-}
\ No newline at end of file
+}
+
+func SortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
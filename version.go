@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a Factorio major.minor.patch version. Missing parts are zero, so
+// Factorio versions such as "2.0" parse as well.
+type Version struct {
+	Major, Minor, Patch int
+}
+
+func ParseVersion(s string) (Version, error) {
+	var version Version
+	parts := strings.Split(strings.TrimSpace(s), ".")
+	if len(parts) > 3 {
+		return version, fmt.Errorf("invalid version %q", s)
+	}
+	fields := []*int{&version.Major, &version.Minor, &version.Patch}
+	for i, part := range parts {
+		n, err := strconv.ParseUint(part, 10, 16)
+		if err != nil {
+			return version, fmt.Errorf("invalid version %q", s)
+		}
+		*fields[i] = int(n)
+	}
+	return version, nil
+}
+
+func (version Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", version.Major, version.Minor, version.Patch)
+}
+
+func (version Version) Compare(other Version) int {
+	switch {
+	case version.Major != other.Major:
+		return Ternary(version.Major < other.Major, -1, 1)
+	case version.Minor != other.Minor:
+		return Ternary(version.Minor < other.Minor, -1, 1)
+	case version.Patch != other.Patch:
+		return Ternary(version.Patch < other.Patch, -1, 1)
+	}
+	return 0
+}
+
+// Matches reports whether the version satisfies the constraint formed by
+// operator and other, e.g. ">=" and 1.2.0. An empty operator always matches.
+func (version Version) Matches(operator string, other Version) bool {
+	cmp := version.Compare(other)
+	switch operator {
+	case "":
+		return true
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case "=":
+		return cmp == 0
+	case ">=":
+		return cmp >= 0
+	case ">":
+		return cmp > 0
+	}
+	return false
+}
+
+// CompareVersions compares two version strings. Invalid versions sort before
+// valid ones and are compared as plain strings between themselves.
+func CompareVersions(a, b string) int {
+	aV, aErr := ParseVersion(a)
+	bV, bErr := ParseVersion(b)
+	switch {
+	case aErr != nil && bErr != nil:
+		return strings.Compare(a, b)
+	case aErr != nil:
+		return -1
+	case bErr != nil:
+		return 1
+	}
+	return aV.Compare(bV)
+}
@@ -0,0 +1,98 @@
+package main
+
+import "testing"
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Version
+		ok    bool
+	}{
+		{"1.2.3", Version{1, 2, 3}, true},
+		{"2.0", Version{2, 0, 0}, true},
+		{"1", Version{1, 0, 0}, true},
+		{" 1.10.0 ", Version{1, 10, 0}, true},
+		{"65535.65535.65535", Version{65535, 65535, 65535}, true},
+		{"65536.0.0", Version{}, false},
+		{"1.99999", Version{}, false},
+		{"", Version{}, false},
+		{"abc", Version{}, false},
+		{"1.2.3.4", Version{}, false},
+		{"1..2", Version{}, false},
+		{"1.2.", Version{}, false},
+		{"-1.0.0", Version{}, false},
+		{"1.2.x", Version{}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			got, err := ParseVersion(test.input)
+			if (err == nil) != test.ok {
+				t.Fatalf("ParseVersion(%q) error = %v, want ok %v", test.input, err, test.ok)
+			}
+			if test.ok && got != test.want {
+				t.Errorf("ParseVersion(%q) = %v, want %v", test.input, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.10", "1.9", 1},
+		{"1.9.0", "1.10.0", -1},
+		{"2.0", "2.0.0", 0},
+		{"1.2.3", "1.2.3", 0},
+		{"1.2.3", "1.2.4", -1},
+		{"2.0.0", "1.99.99", 1},
+		{"0.0.10", "0.0.9", 1},
+		// Invalid versions sort before valid ones and by string between themselves.
+		{"", "0.0.0", -1},
+		{"0.0.1", "garbage", 1},
+		{"65536.0.0", "1.0.0", -1},
+		{"", "", 0},
+		{"abc", "abd", -1},
+	}
+	for _, test := range tests {
+		t.Run(test.a+"_"+test.b, func(t *testing.T) {
+			if got := CompareVersions(test.a, test.b); got != test.want {
+				t.Errorf("CompareVersions(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+			}
+			if got := CompareVersions(test.b, test.a); got != -test.want {
+				t.Errorf("CompareVersions(%q, %q) = %d, want %d", test.b, test.a, got, -test.want)
+			}
+		})
+	}
+}
+
+func TestVersionMatches(t *testing.T) {
+	lower, equal, higher := Version{1, 1, 0}, Version{1, 2, 0}, Version{1, 10, 0}
+	constraint := Version{1, 2, 0}
+	tests := []struct {
+		operator             string
+		lower, equal, higher bool
+	}{
+		{"", true, true, true},
+		{"<", true, false, false},
+		{"<=", true, true, false},
+		{"=", false, true, false},
+		{">=", false, true, true},
+		{">", false, false, true},
+		{"!=", false, false, false},
+		{"~>", false, false, false},
+	}
+	for _, test := range tests {
+		t.Run(test.operator, func(t *testing.T) {
+			for _, c := range []struct {
+				version Version
+				want    bool
+			}{{lower, test.lower}, {equal, test.equal}, {higher, test.higher}} {
+				if got := c.version.Matches(test.operator, constraint); got != c.want {
+					t.Errorf("%v %s %v = %v, want %v", c.version, test.operator, constraint, got, c.want)
+				}
+			}
+		})
+	}
+}
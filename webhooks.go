@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type Webhook struct {
+	ID    string `json:"id"`
+	Token string `json:"token"`
+	// Created is set when the bot created the webhook and should delete it
+	// once it is no longer used.
+	Created bool `json:"created"`
+}
+
+// ParseWebhookURL extracts the ID and token from a Discord webhook URL such as
+// https://discord.com/api/webhooks/<id>/<token>.
+func ParseWebhookURL(rawURL string) (*Webhook, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i := 0; i+2 < len(parts); i++ {
+		if parts[i] == "webhooks" {
+			return &Webhook{ID: parts[i+1], Token: parts[i+2]}, nil
+		}
+	}
+	return nil, fmt.Errorf("%s is not a webhook URL", rawURL)
+}
+
+func IsUnknownWebhook(err error) bool {
+	var restErr *discordgo.RESTError
+	if !errors.As(err, &restErr) {
+		return false
+	}
+	if restErr.Message != nil && restErr.Message.Code == discordgo.ErrCodeUnknownWebhook {
+		return true
+	}
+	return restErr.Response != nil && restErr.Response.StatusCode == http.StatusNotFound
+}
+
+// WebhookUsername makes a name acceptable to Discord, which rejects webhook
+// names over 80 characters or containing "discord" or "clyde".
+func WebhookUsername(name string) string {
+	lower := strings.ToLower(name)
+	if strings.Contains(lower, "discord") || strings.Contains(lower, "clyde") {
+		return ""
+	}
+	return Truncate(strings.TrimSpace(name), 80)
+}
+
+// SendToRoute posts a message to a route's channel, through the route's webhook
+// if it has one. If the webhook was deleted it is removed from both route and
+// the stored guild, and the message is sent by the bot instead.
+func SendToRoute(m Messenger, guildID, routeName string, route *Route, data *discordgo.MessageSend, username, avatarURL string) error {
+	if route.Webhook != nil {
+		_, err := m.ExecuteWebhook(route.Webhook.ID, route.Webhook.Token, &discordgo.WebhookParams{
+			Content:         data.Content,
+			Username:        WebhookUsername(username),
+			AvatarURL:       avatarURL,
+			Embeds:          data.Embeds,
+			AllowedMentions: data.AllowedMentions,
+		})
+		if err == nil || !IsUnknownWebhook(err) {
+			return err
+		}
+
+		slog.Warn("Webhook was deleted, falling back to bot messages", "guild", guildID, "route", routeName)
+		webhookID := route.Webhook.ID
+		route.Webhook = nil
+		err = guildStore.Update(guildID, func(guildData *GuildData) error {
+			if r := guildData.GetRoute(routeName); r != nil && r.Webhook != nil && r.Webhook.ID == webhookID {
+				r.Webhook = nil
+			}
+			return nil
+		})
+		if err != nil {
+			slog.Error("Could not update guild", "guild", guildID, "err", err)
+		}
+	}
+
+	_, err := m.SendMessage(route.Channel, data)
+	return err
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestSendToRouteWebhook(t *testing.T) {
+	setupTest(t)
+	m := &recordingMessenger{}
+	webhook, err := m.CreateWebhook("updates", "Mod Portal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	route := &Route{Channel: "updates", Webhook: &Webhook{ID: webhook.ID, Token: webhook.Token}}
+
+	data := &discordgo.MessageSend{Embeds: []*discordgo.MessageEmbed{{Title: "Example Mod"}}}
+	if err := SendToRoute(m, "guild", "", route, data, "Example Mod", "https://example.com/thumb.png"); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Messages) != 0 || len(m.WebhookMessages) != 1 {
+		t.Fatalf("sent %d bot and %d webhook messages, want one webhook message", len(m.Messages), len(m.WebhookMessages))
+	}
+	if params := m.WebhookMessages[0].Data; params.Username != "Example Mod" || params.AvatarURL != "https://example.com/thumb.png" {
+		t.Errorf("webhook sent as %q with avatar %q", params.Username, params.AvatarURL)
+	}
+}
+
+func TestSendToRouteDeletedWebhook(t *testing.T) {
+	setupTest(t)
+	err := guildStore.Update("guild", func(guildData *GuildData) error {
+		guildData.TrackEnabled = true
+		guildData.Channel = "updates"
+		guildData.Webhook = &Webhook{ID: "deleted", Token: "token"}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	guildData, err := guildStore.Get("guild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fullMod, err := portal.GetFullMod("example-mod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var releases []SpecificRelease
+	for _, release := range fullMod.Releases {
+		releases = append(releases, SpecificRelease{Mod: fullMod, Release: release})
+	}
+
+	m := &recordingMessenger{}
+	route := guildData.GetRoute("")
+	DeliverReleases(m, "guild", "", route, guildData, releases, map[string]bool{})
+
+	// Every release falls back to a bot message, but the deleted webhook is
+	// only tried once.
+	if len(m.Messages) != len(releases) {
+		t.Fatalf("sent %d bot messages, want %d", len(m.Messages), len(releases))
+	}
+	for _, message := range m.Messages {
+		if message.ChannelID != "updates" {
+			t.Errorf("message sent to %q, want updates", message.ChannelID)
+		}
+	}
+	if len(m.DeadWebhooks) != 1 {
+		t.Errorf("deleted webhook executed %d times, want 1", len(m.DeadWebhooks))
+	}
+	if route.Webhook != nil {
+		t.Error("deleted webhook kept on the route")
+	}
+
+	guildData, err = guildStore.Get("guild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if guildData.Webhook != nil {
+		t.Errorf("deleted webhook kept in the guild store: %+v", guildData.Webhook)
+	}
+}